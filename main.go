@@ -1,56 +1,71 @@
 package main
 
 import (
-	"io"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/cli"
 	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/config"
 	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/hash"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/logger"
 	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/merger"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/overrides"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/progressbar"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/rpf"
 	"github.com/charmbracelet/huh"
-	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/log"
 )
 
-var Flags flags.Flags
-
 func main() {
-	appFlags, err := config.LoadConfig()
-	logFile := "merger.log"
-	// Clear existing log file by recreating it
-	f, err := os.OpenFile(logFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	// Any argument selects a headless subcommand, the menu only runs without one.
+	// Subcommands log to stderr so their stdout can be piped.
+	if len(os.Args) > 1 {
+		f, err := logger.Open(os.Stderr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		code := cli.Run(os.Args[1:])
+		f.Close()
+		os.Exit(code)
+	}
+
+	f, err := logger.Open(os.Stdout)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer f.Close()
-	fileWriter := io.MultiWriter(os.Stdout, f)
-	log.SetOutput(fileWriter)
 
-	if appFlags == nil {
+	hash.DictionaryPath = filepath.Join(filepath.Dir(config.Path()), hash.DictionaryFile)
+	overrides.DefaultPath = filepath.Join(filepath.Dir(config.Path()), overrides.FileName)
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg == nil {
 		// First time setup
-		appFlags = &flags.Flags{}
+		appFlags := &flags.Flags{}
 		if err := initialSetup(appFlags); err != nil {
 			log.Fatal(err)
 		}
-		if err := config.SaveConfig(appFlags); err != nil {
+		cfg = config.New(appFlags)
+		if err := config.SaveConfig(cfg); err != nil {
 			log.Fatal(err)
 		}
 	}
 
-	if absPath, err := filepath.Abs(appFlags.OutputPath); err == nil {
-		appFlags.OutputPath = absPath
-	}
-	if absPath, err := filepath.Abs(appFlags.InputPath); err == nil {
-		appFlags.InputPath = absPath
-	}
-
 	for {
-		mainMenu := []string{"Start Merge Process", "Edit Settings", "Exit"}
+		appFlags := cfg.Active()
+		mainMenu := []string{"Start Merge Process", "Extract RPF File", "Edit Settings", "Profiles", "Exit"}
 		var selected string
 
 		form := huh.NewSelect[string]().
 			Title("FiveM Cars Merger").
+			Description("Profile: " + cfg.ActiveProfile).
 			Options(huh.NewOptions(mainMenu...)...).
 			Value(&selected)
 
@@ -61,8 +76,8 @@ func main() {
 
 		switch selected {
 		case "Start Merge Process":
-			ConfigureLogger()
-			carsMerger := merger.New(*appFlags)
+			logger.Configure(appFlags.Verbose)
+			carsMerger := merger.New(resolvePaths(appFlags))
 			if err := carsMerger.Merge(); err != nil {
 				log.Error("Merge failed:", err)
 				continue
@@ -71,80 +86,262 @@ func main() {
 			if err := editSettings(appFlags); err != nil {
 				log.Fatal(err)
 			}
-			if err := config.SaveConfig(appFlags); err != nil {
+			if err := config.SaveConfig(cfg); err != nil {
 				log.Fatal(err)
 			}
-		// case "Extract RPF File":
-		// 	extractor := rpf.Extractor{
-		// 		CachePath: filepath.Join("assets", "out"), // Add 'extracted' subfolder
-		// 	}
-
-		// 	if err := extractor.Extract(filepath.Join("assets", "dlc.rpf")); err != nil {
-		// 		log.Fatal(err)
-		// 	}
-		// 	log.Info("RPF extraction completed successfully")
+		case "Profiles":
+			if err := manageProfiles(cfg); err != nil {
+				log.Error("Profiles:", err)
+				continue
+			}
+			if err := config.SaveConfig(cfg); err != nil {
+				log.Fatal(err)
+			}
+		case "Extract RPF File":
+			logger.Configure(appFlags.Verbose)
+			if err := extractRPF(); err != nil {
+				log.Error("Extraction failed:", err)
+				continue
+			}
 		case "Exit":
 			return
 		}
 	}
 }
 
-func initialSetup(flags *flags.Flags) error {
-	return huh.NewForm(
-		huh.NewGroup(
-			huh.NewInput().
-				Title("Input Path").
-				Description("Path to all cars").
-				Placeholder("cars").
-				Value(&flags.InputPath),
-			huh.NewInput().
-				Title("Output Path").
-				Description("Output path for merged cars").
-				Placeholder("merged-cars").
-				Value(&flags.OutputPath),
-			huh.NewConfirm().
-				Title("Enable Verbose Logging").
-				Value(&flags.Verbose),
-			huh.NewConfirm().
-				Title("Clean Output Directory").
-				Value(&flags.Clean),
-		).Title("Initial Configuration"),
-	).Run()
+// resolvePaths returns a copy of a profile with its paths resolved like the command line does,
+// relative to the config file. The profile keeps its paths as written, so they are saved that way.
+func resolvePaths(appFlags *flags.Flags) flags.Flags {
+	resolved := *appFlags
+	resolved.OutputPath = config.ResolvePath(appFlags.OutputPath)
+	resolved.InputPath = config.ResolvePath(appFlags.InputPath)
+	resolved.ExtraInputs = config.ResolvePaths(appFlags.ExtraInputs)
+	resolved.Overrides = config.ResolvePath(appFlags.Overrides)
+	return resolved
 }
 
-func editSettings(flags *flags.Flags) error {
-	return huh.NewForm(
+func manageProfiles(cfg *config.Config) error {
+	var action string
+	err := huh.NewSelect[string]().
+		Title("Profiles").
+		Description("Active profile: " + cfg.ActiveProfile).
+		Options(huh.NewOptions("Switch Profile", "Create Profile", "Duplicate Profile", "Delete Profile", "Back")...).
+		Value(&action).
+		Run()
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "Switch Profile":
+		name, err := selectProfile(cfg, "Switch Profile")
+		if err != nil {
+			return err
+		}
+		return cfg.SetActive(name)
+	case "Create Profile":
+		name, err := profileName(cfg, "Create Profile")
+		if err != nil {
+			return err
+		}
+		appFlags := &flags.Flags{}
+		if err := editSettings(appFlags); err != nil {
+			return err
+		}
+		if err := cfg.AddProfile(name, appFlags); err != nil {
+			return err
+		}
+		return cfg.SetActive(name)
+	case "Duplicate Profile":
+		name, err := profileName(cfg, "Duplicate "+cfg.ActiveProfile)
+		if err != nil {
+			return err
+		}
+		if err := cfg.DuplicateProfile(cfg.ActiveProfile, name); err != nil {
+			return err
+		}
+		return cfg.SetActive(name)
+	case "Delete Profile":
+		name, err := selectProfile(cfg, "Delete Profile")
+		if err != nil {
+			return err
+		}
+		confirmed := false
+		err = huh.NewConfirm().
+			Title("Delete profile " + name + "?").
+			Value(&confirmed).
+			Run()
+		if err != nil || !confirmed {
+			return err
+		}
+		return cfg.DeleteProfile(name)
+	}
+	return nil
+}
+
+func selectProfile(cfg *config.Config, title string) (string, error) {
+	name := cfg.ActiveProfile
+	err := huh.NewSelect[string]().
+		Title(title).
+		Options(huh.NewOptions(cfg.ProfileNames()...)...).
+		Value(&name).
+		Run()
+	return name, err
+}
+
+func profileName(cfg *config.Config, title string) (string, error) {
+	var name string
+	err := huh.NewInput().
+		Title(title).
+		Description("Name of the new profile").
+		Validate(func(value string) error {
+			if strings.TrimSpace(value) == "" {
+				return errors.New("name is empty")
+			}
+			if _, err := cfg.Profile(strings.TrimSpace(value)); err == nil {
+				return fmt.Errorf("profile %q already exists", strings.TrimSpace(value))
+			}
+			return nil
+		}).
+		Value(&name).
+		Run()
+	return strings.TrimSpace(name), err
+}
+
+func extractRPF() error {
+	var archive, destination, only string
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewFilePicker().
+				Title("RPF Archive").
+				Description("Archive to extract").
+				AllowedTypes([]string{".rpf"}).
+				Picking(true).
+				Value(&archive),
+		),
 		huh.NewGroup(
-			huh.NewInput().
-				Title("Input Path").
-				Description("Path to all cars").
-				Value(&flags.InputPath),
 			huh.NewInput().
 				Title("Output Path").
-				Description("Output path for merged cars").
-				Value(&flags.OutputPath),
-			huh.NewConfirm().
-				Title("Enable Verbose Logging").
-				Value(&flags.Verbose),
-			huh.NewConfirm().
-				Title("Clean Output Directory").
-				Value(&flags.Clean),
-		).Title("Edit Settings"),
+				Description("Leave empty to extract next to the archive").
+				Value(&destination),
+			huh.NewInput().
+				Title("Only Extensions").
+				Description("Comma separated, e.g. .yft,.ytd,.meta. Leave empty to extract everything").
+				Value(&only),
+		).Title("Extract RPF File"),
 	).Run()
+	if err != nil {
+		return err
+	}
+
+	if destination == "" {
+		destination = strings.TrimSuffix(archive, filepath.Ext(archive))
+	}
+	var extensions []string
+	for _, extension := range strings.Split(only, ",") {
+		if extension = strings.TrimSpace(extension); extension != "" {
+			extensions = append(extensions, extension)
+		}
+	}
+
+	bar := progressbar.New(os.Stdout, "Extracting")
+	extractor := &rpf.Extractor{
+		CachePath:  destination,
+		Extensions: extensions,
+		OnProgress: bar.Update,
+	}
+	dictionary, dictionaryErr := hash.LoadDictionary(hash.DictionaryPath)
+	if dictionaryErr == nil {
+		extractor.Dictionary = dictionary
+	}
+	err = extractor.Extract(archive)
+	bar.Finish()
+	if err != nil {
+		return err
+	}
+	if dictionaryErr == nil {
+		dictionaryErr = dictionary.Save(hash.DictionaryPath)
+	}
+	if dictionaryErr != nil {
+		log.Warn("Failed to update the hash dictionary", "path", hash.DictionaryPath, "err", dictionaryErr)
+	}
+
+	extractor.LogSummary()
+	return nil
 }
 
-func ConfigureLogger() {
-	style := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(lipgloss.Color("#ff7df9"))
+func initialSetup(flags *flags.Flags) error {
+	problems := ""
+	for {
+		err := huh.NewForm(
+			huh.NewGroup(
+				huh.NewInput().
+					Title("Input Path").
+					Description("Path to all cars").
+					Placeholder("cars").
+					Value(&flags.InputPath),
+				huh.NewInput().
+					Title("Output Path").
+					Description("Output path for merged cars").
+					Placeholder("merged-cars").
+					Value(&flags.OutputPath),
+				huh.NewConfirm().
+					Title("Enable Verbose Logging").
+					Value(&flags.Verbose),
+				huh.NewConfirm().
+					Title("Clean Output Directory").
+					Value(&flags.Clean),
+			).Title("Initial Configuration").Description(problems),
+		).Run()
+		if err != nil {
+			return err
+		}
+		if problems = pathProblems(flags); problems == "" {
+			return nil
+		}
+	}
+}
 
-	log.SetFormatter(log.TextFormatter)
-	log.SetReportCaller(true)
-	log.SetPrefix(style.Render("FiveMCarsMerger"))
+func editSettings(flags *flags.Flags) error {
+	problems := ""
+	for {
+		err := huh.NewForm(
+			huh.NewGroup(
+				huh.NewInput().
+					Title("Input Path").
+					Description("Path to all cars").
+					Value(&flags.InputPath),
+				huh.NewInput().
+					Title("Output Path").
+					Description("Output path for merged cars").
+					Value(&flags.OutputPath),
+				huh.NewConfirm().
+					Title("Enable Verbose Logging").
+					Value(&flags.Verbose),
+				huh.NewConfirm().
+					Title("Clean Output Directory").
+					Value(&flags.Clean),
+				huh.NewConfirm().
+					Title("Strict Mode").
+					Description("Fail on missing stream or data files, unknown metas and conflicts").
+					Value(&flags.Strict),
+			).Title("Edit Settings").Description(problems),
+		).Run()
+		if err != nil {
+			return err
+		}
+		if problems = pathProblems(flags); problems == "" {
+			return nil
+		}
+	}
+}
 
-	if Flags.Verbose {
-		log.SetLevel(log.DebugLevel)
-	} else {
-		log.SetLevel(log.InfoLevel)
+// pathProblems lists every problem with the paths of appFlags for the description of a
+// settings form, or returns an empty string when they are fine.
+func pathProblems(appFlags *flags.Flags) string {
+	var paths *config.PathsError
+	if !errors.As(config.CheckPaths(resolvePaths(appFlags)), &paths) {
+		return ""
 	}
+	return "Fix these settings first:\n- " + strings.Join(paths.Problems, "\n- ")
 }
@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/plan"
+	fileutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/file"
+	poolutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/pool"
+)
+
+// FileName is the build cache, which is kept in the output directory.
+const FileName = ".merger-cache.json"
+
+// Entry is the source a file of the output was copied from, as it was at the time.
+type Entry struct {
+	Source  string
+	Size    int64
+	ModTime time.Time
+	Hash    string
+	Rename  *plan.Rename `json:",omitempty"`
+}
+
+// Cache records how every file of a merged output was made, so a re-merge only copies
+// the files whose source changed.
+type Cache struct {
+	Manifest     []string         // manifest entries fxmanifest.lua was generated from
+	ManifestHash string           // hash of fxmanifest.lua, so edits by add or remove are noticed
+	Files        map[string]Entry // destination relative to the output path -> source
+}
+
+// Load reads the cache of the output at outputPath. It returns nil when there is none.
+func Load(outputPath string) (*Cache, error) {
+	data, err := os.ReadFile(filepath.Join(outputPath, FileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Cache{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse build cache %s: %w", filepath.Join(outputPath, FileName), err)
+	}
+	if c.Files == nil {
+		c.Files = make(map[string]Entry)
+	}
+	return c, nil
+}
+
+// New returns the cache of an output made by ops, with the entries returned by Check.
+func New(manifest []string, ops []plan.Operation, entries []Entry) *Cache {
+	c := &Cache{Manifest: manifest, Files: make(map[string]Entry)}
+	for i, op := range ops {
+		c.Files[op.Destination] = entries[i]
+	}
+	return c
+}
+
+// Save writes the cache into the output at outputPath, after fxmanifest.lua was generated.
+func (c *Cache) Save(outputPath string) error {
+	manifestHash, err := fileutils.HashFile(filepath.Join(outputPath, "fxmanifest.lua"))
+	if err != nil {
+		return err
+	}
+	c.ManifestHash = manifestHash
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(outputPath, FileName), data, 0644)
+}
+
+// Check returns the entry of the source of every operation and whether the output of the
+// operation is still up to date. Sources are only hashed when their size or modification
+// time changed since the cache was written. A nil cache has nothing up to date, and neither
+// has a destination several operations write, so the last one still wins.
+func (c *Cache) Check(outputPath string, ops []plan.Operation, workers int) ([]Entry, []bool, error) {
+	entries := make([]Entry, len(ops))
+	upToDate := make([]bool, len(ops))
+	writers := make(map[string]int)
+	for _, op := range ops {
+		writers[op.Destination]++
+	}
+
+	err := poolutils.Run(workers, len(ops), func(i int) error {
+		op := ops[i]
+		info, err := os.Stat(op.Source)
+		if err != nil {
+			return err
+		}
+		entry := Entry{Source: op.Source, Size: info.Size(), ModTime: info.ModTime(), Rename: op.Rename}
+
+		var cached Entry
+		var ok bool
+		if c != nil {
+			cached, ok = c.Files[op.Destination]
+		}
+		_, destErr := os.Stat(filepath.Join(outputPath, filepath.FromSlash(op.Destination)))
+		ok = ok && writers[op.Destination] == 1 && destErr == nil && cached.Source == op.Source && sameRename(cached.Rename, op.Rename)
+
+		if ok && cached.Size == entry.Size && cached.ModTime.Equal(entry.ModTime) {
+			entries[i], upToDate[i] = cached, true
+			return nil
+		}
+
+		// Touched, restored or new files are compared by content
+		if entry.Hash, err = fileutils.HashFile(op.Source); err != nil {
+			return err
+		}
+		entries[i], upToDate[i] = entry, ok && cached.Size == entry.Size && cached.Hash == entry.Hash
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return entries, upToDate, nil
+}
+
+// Stale returns the destinations in the cache that none of ops writes anymore, sorted.
+func (c *Cache) Stale(ops []plan.Operation) []string {
+	if c == nil {
+		return nil
+	}
+	written := make(map[string]bool)
+	for _, op := range ops {
+		written[op.Destination] = true
+	}
+
+	var stale []string
+	for destination := range c.Files {
+		if !written[destination] {
+			stale = append(stale, destination)
+		}
+	}
+	sort.Strings(stale)
+	return stale
+}
+
+// SameManifest reports whether the fxmanifest.lua of the output at outputPath was generated
+// from the manifest entries and hasn't been changed since.
+func (c *Cache) SameManifest(outputPath string, manifest []string) bool {
+	if c == nil || len(c.Manifest) != len(manifest) {
+		return false
+	}
+	if hash, err := fileutils.HashFile(filepath.Join(outputPath, "fxmanifest.lua")); err != nil || hash != c.ManifestHash {
+		return false
+	}
+	for i := range manifest {
+		if c.Manifest[i] != manifest[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sameRename(a *plan.Rename, b *plan.Rename) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,231 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/plan"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, source string, output string) // runs after the first merge
+		ops    func(source string) []plan.Operation
+		want   []bool
+	}{
+		{
+			name: "unchanged source",
+			want: []bool{true},
+		},
+		{
+			name: "touched source with the same content",
+			change: func(t *testing.T, source string, output string) {
+				later := time.Now().Add(time.Hour)
+				if err := os.Chtimes(filepath.Join(source, "adder.yft"), later, later); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []bool{true},
+		},
+		{
+			name: "edited source",
+			change: func(t *testing.T, source string, output string) {
+				writeFile(t, filepath.Join(source, "adder.yft"), "new model")
+			},
+			want: []bool{false},
+		},
+		{
+			name: "edited source of the same size",
+			change: func(t *testing.T, source string, output string) {
+				writeFile(t, filepath.Join(source, "adder.yft"), "MODEL")
+				later := time.Now().Add(time.Hour)
+				os.Chtimes(filepath.Join(source, "adder.yft"), later, later)
+			},
+			want: []bool{false},
+		},
+		{
+			name: "deleted output",
+			change: func(t *testing.T, source string, output string) {
+				if err := os.Remove(filepath.Join(output, "stream", "adder.yft")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: []bool{false},
+		},
+		{
+			name: "other source for the destination",
+			change: func(t *testing.T, source string, output string) {
+				writeFile(t, filepath.Join(source, "other.yft"), "model")
+			},
+			ops: func(source string) []plan.Operation {
+				return []plan.Operation{{Source: filepath.Join(source, "other.yft"), Destination: "stream/adder.yft"}}
+			},
+			want: []bool{false},
+		},
+		{
+			name: "renamed since",
+			ops: func(source string) []plan.Operation {
+				return []plan.Operation{{Source: filepath.Join(source, "adder.yft"), Destination: "stream/adder.yft", Rename: &plan.Rename{From: "adder", To: "myadder"}}}
+			},
+			want: []bool{false},
+		},
+		{
+			name: "destination written twice",
+			ops: func(source string) []plan.Operation {
+				return []plan.Operation{
+					{Source: filepath.Join(source, "adder.yft"), Destination: "stream/adder.yft"},
+					{Source: filepath.Join(source, "adder.yft"), Destination: "stream/adder.yft"},
+				}
+			},
+			want: []bool{false, false},
+		},
+		{
+			name: "new file",
+			change: func(t *testing.T, source string, output string) {
+				writeFile(t, filepath.Join(source, "adder.ytd"), "textures")
+			},
+			ops: func(source string) []plan.Operation {
+				return []plan.Operation{
+					{Source: filepath.Join(source, "adder.yft"), Destination: "stream/adder.yft"},
+					{Source: filepath.Join(source, "adder.ytd"), Destination: "stream/adder.ytd"},
+				}
+			},
+			want: []bool{true, false},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source, output := t.TempDir(), t.TempDir()
+			writeFile(t, filepath.Join(source, "adder.yft"), "model")
+			writeFile(t, filepath.Join(output, "stream", "adder.yft"), "model")
+			writeFile(t, filepath.Join(output, "fxmanifest.lua"), "fx_version 'cerulean'")
+
+			// The first merge copies everything and writes the cache
+			first := []plan.Operation{{Source: filepath.Join(source, "adder.yft"), Destination: "stream/adder.yft"}}
+			entries, upToDate, err := (*Cache)(nil).Check(output, first, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if upToDate[0] {
+				t.Fatal("nil cache reported a file as up to date")
+			}
+			if err := New(nil, first, entries).Save(output); err != nil {
+				t.Fatal(err)
+			}
+
+			if test.change != nil {
+				test.change(t, source, output)
+			}
+			ops := first
+			if test.ops != nil {
+				ops = test.ops(source)
+			}
+
+			loaded, err := Load(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, upToDate, err = loaded.Check(output, ops, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := range test.want {
+				if upToDate[i] != test.want[i] {
+					t.Errorf("upToDate[%d] = %v, want %v", i, upToDate[i], test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCheckMissingSource(t *testing.T) {
+	ops := []plan.Operation{{Source: filepath.Join(t.TempDir(), "missing.yft"), Destination: "stream/missing.yft"}}
+	if _, _, err := (*Cache)(nil).Check(t.TempDir(), ops, 1); err == nil {
+		t.Error("Check() = nil, want an error for a missing source")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	output := t.TempDir()
+	if c, err := Load(output); c != nil || err != nil {
+		t.Errorf("Load() without a cache = %v, %v, want nil, nil", c, err)
+	}
+
+	writeFile(t, filepath.Join(output, FileName), "{not json")
+	if _, err := Load(output); err == nil {
+		t.Error("Load() of a broken cache = nil, want an error")
+	}
+}
+
+func TestStale(t *testing.T) {
+	c := &Cache{Files: map[string]Entry{
+		"stream/zentorno.yft":                  {},
+		"stream/adder.yft":                     {},
+		"data/vehicles/vehicles_zentorno.meta": {},
+	}}
+	ops := []plan.Operation{{Destination: "stream/adder.yft"}}
+
+	got := c.Stale(ops)
+	want := []string{"data/vehicles/vehicles_zentorno.meta", "stream/zentorno.yft"}
+	if len(got) != len(want) {
+		t.Fatalf("Stale() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Stale() = %v, want %v", got, want)
+		}
+	}
+
+	if stale := (*Cache)(nil).Stale(ops); stale != nil {
+		t.Errorf("nil cache Stale() = %v, want nil", stale)
+	}
+}
+
+func TestSameManifest(t *testing.T) {
+	output := t.TempDir()
+	writeFile(t, filepath.Join(output, "fxmanifest.lua"), "fx_version 'cerulean'")
+	c := New([]string{"data/vehicles"}, nil, nil)
+	if err := c.Save(output); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		manifest []string
+		edit     string
+		want     bool
+	}{
+		{name: "same entries", manifest: []string{"data/vehicles"}, want: true},
+		{name: "entry added", manifest: []string{"data/vehicles", "data/handling"}, want: false},
+		{name: "entry changed", manifest: []string{"data/handling"}, want: false},
+		{name: "file edited", manifest: []string{"data/vehicles"}, edit: "fx_version 'bodacious'", want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.edit != "" {
+				writeFile(t, filepath.Join(output, "fxmanifest.lua"), test.edit)
+			}
+			if got := c.SameManifest(output, test.manifest); got != test.want {
+				t.Errorf("SameManifest() = %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	if (*Cache)(nil).SameManifest(output, nil) {
+		t.Error("nil cache SameManifest() = true, want false")
+	}
+}
@@ -25,8 +25,21 @@ func New(_flags flags.Flags) CarFinder {
 }
 
 func (cf *carFinder) FindValidCars(dataFileCars []string, streamFileCars []string) []string {
-	var validCars []string
-	var noStreamCars, noDataCars []string
+	validCars, noStreamCars, noDataCars := ClassifyCars(dataFileCars, streamFileCars)
+
+	if len(noStreamCars) > 0 {
+		log.Warn("Following cars have no stream files", "cars", noStreamCars)
+	}
+	if len(noDataCars) > 0 {
+		log.Warn("Following cars have no data files", "cars", noDataCars)
+	}
+
+	return validCars
+}
+
+// ClassifyCars pairs the cars found in data files with the cars found in stream files.
+// Valid cars appear once for each side they were found on.
+func ClassifyCars(dataFileCars []string, streamFileCars []string) (validCars []string, noStreamCars []string, noDataCars []string) {
 	for _, dataFileCar := range dataFileCars {
 		if sliceutils.ContainsElement(streamFileCars, dataFileCar) {
 			validCars = append(validCars, dataFileCar)
@@ -43,14 +56,7 @@ func (cf *carFinder) FindValidCars(dataFileCars []string, streamFileCars []strin
 		}
 	}
 
-	if len(noStreamCars) > 0 {
-		log.Warn("Following cars have no stream files", "cars", noStreamCars)
-	}
-	if len(noDataCars) > 0 {
-		log.Warn("Following cars have no data files", "cars", noDataCars)
-	}
-
-	return validCars
+	return validCars, noStreamCars, noDataCars
 }
 
 func (cf *carFinder) FindStreamFileCars() ([]string, error) {
@@ -63,8 +69,8 @@ func (cf *carFinder) FindStreamFileCars() ([]string, error) {
 	}
 
 	for _, file := range files {
-		if strings.HasSuffix(file.Name(), ".yft") && !strings.Contains(file.Name(), "_") {
-			streamFileCars = append(streamFileCars, strings.ToLower(file.Name()[:len(file.Name())-4]))
+		if car, ok := StreamFileCar(file.Name()); ok {
+			streamFileCars = append(streamFileCars, car)
 		}
 	}
 
@@ -82,30 +88,47 @@ func (cf *carFinder) FindDataFileCars() ([]string, error) {
 
 	for _, file := range files {
 		if strings.HasPrefix(file.Name(), "vehicles_") {
-			xmlFile, err := os.Open(outputDataPath + "/" + file.Name())
+			modelNames, err := ReadModelNames(outputDataPath + "/" + file.Name())
 			if err != nil {
 				return nil, err
 			}
-			byteValue, err := ioutil.ReadAll(xmlFile)
-			if err != nil {
-				return nil, err
-			}
-			re1 := regexp.MustCompile(`<modelName.*?>(.*)</modelName>`)
-
-			matches := re1.FindAllStringSubmatch(string(byteValue), -1)
 
-			for _, v := range matches {
-				if !sliceutils.ContainsElement(dataFileCars, strings.ToLower(v[1])) {
-					dataFileCars = append(dataFileCars, strings.ToLower(v[1]))
+			for _, modelName := range modelNames {
+				if !sliceutils.ContainsElement(dataFileCars, modelName) {
+					dataFileCars = append(dataFileCars, modelName)
 				}
 			}
-
-			err = xmlFile.Close()
-			if err != nil {
-				return nil, err
-			}
 		}
 	}
 
 	return dataFileCars, nil
 }
+
+// StreamFileCar returns the car a stream file belongs to when it is the main model of a car.
+func StreamFileCar(name string) (string, bool) {
+	if strings.HasSuffix(name, ".yft") && !strings.Contains(name, "_") {
+		return strings.ToLower(name[:len(name)-4]), true
+	}
+	return "", false
+}
+
+// ReadModelNames returns the lowercase model names declared in a vehicles.meta.
+func ReadModelNames(path string) ([]string, error) {
+	xmlFile, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer xmlFile.Close()
+
+	byteValue, err := ioutil.ReadAll(xmlFile)
+	if err != nil {
+		return nil, err
+	}
+	re1 := regexp.MustCompile(`<modelName.*?>(.*)</modelName>`)
+
+	var modelNames []string
+	for _, v := range re1.FindAllStringSubmatch(string(byteValue), -1) {
+		modelNames = append(modelNames, strings.ToLower(v[1]))
+	}
+	return modelNames, nil
+}
@@ -0,0 +1,242 @@
+package carfinder
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/dft"
+	sliceutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/slice"
+	"github.com/charmbracelet/log"
+)
+
+// Car is everything found in an input tree that belongs to one model.
+type Car struct {
+	Model       string
+	Folder      string // top level folder below the input path
+	StreamFiles []string
+	DataFiles   []string
+	DataTypes   []string
+	AudioFiles  []string
+	AudioPacks  []string
+	Missing     []string
+	AudioName   string // audioNameHash of the vehicle
+
+	dirs []string
+}
+
+// GroupCars associates the scanned files of an input tree with the cars they belong to.
+// Data files belong to the cars of the vehicles.meta in their directory, like the copier
+// renames them, stream files are matched by model name prefix and audio packs by the
+// audioNameHash of the vehicle. Files that match no car are returned separately.
+func GroupCars(root string, streamFiles []dft.StreamFile, dataFiles []dft.DataFile, audioFiles []dft.AudioFile) ([]*Car, []string) {
+	var cars []*Car
+	var unassigned []string
+	byFolder := make(map[string][]*Car)
+
+	addCar := func(car *Car) {
+		cars = append(cars, car)
+		byFolder[car.Folder] = append(byFolder[car.Folder], car)
+	}
+
+	for _, dataFile := range dataFiles {
+		if dataFile.Type != dft.VEHICLES {
+			continue
+		}
+		vehicles, err := ReadVehicles(dataFile.Path)
+		if err != nil {
+			log.Debug("Failed to read vehicles.meta", "path", dataFile.Path, "err", err)
+			continue
+		}
+
+		folder := topLevelFolder(root, dataFile.Path)
+		for _, vehicle := range vehicles {
+			car := findCar(byFolder[folder], strings.ToLower(vehicle.ModelName))
+			if car == nil {
+				car = &Car{
+					Model:     strings.ToLower(vehicle.ModelName),
+					Folder:    folder,
+					AudioName: strings.ToLower(vehicle.AudioNameHash),
+				}
+				addCar(car)
+			}
+			car.dirs = append(car.dirs, filepath.Dir(dataFile.Path))
+		}
+	}
+
+	// Stream only cars need to exist before files are matched against them
+	for _, streamFile := range streamFiles {
+		folder := topLevelFolder(root, streamFile.Path)
+		if model, ok := StreamFileCar(streamFile.Name); ok && matchStreamFile(byFolder[folder], streamFile.Name) == nil {
+			addCar(&Car{Model: model, Folder: folder})
+		}
+	}
+
+	for _, dataFile := range dataFiles {
+		var owners []*Car
+		for _, car := range byFolder[topLevelFolder(root, dataFile.Path)] {
+			if sliceutils.ContainsElement(car.dirs, filepath.Dir(dataFile.Path)) {
+				owners = append(owners, car)
+			}
+		}
+		if len(owners) == 0 {
+			owners = onlyCar(byFolder[topLevelFolder(root, dataFile.Path)])
+		}
+		if len(owners) == 0 {
+			unassigned = append(unassigned, dataFile.Path)
+		}
+		for _, car := range owners {
+			car.DataFiles = append(car.DataFiles, dataFile.Path)
+			if !sliceutils.ContainsElement(car.DataTypes, dataFile.Type.String()) {
+				car.DataTypes = append(car.DataTypes, dataFile.Type.String())
+			}
+		}
+	}
+
+	for _, streamFile := range streamFiles {
+		folderCars := byFolder[topLevelFolder(root, streamFile.Path)]
+		owners := onlyCar(folderCars)
+		if car := matchStreamFile(folderCars, streamFile.Name); car != nil {
+			owners = []*Car{car}
+		}
+		if len(owners) == 0 {
+			unassigned = append(unassigned, streamFile.Path)
+		}
+		for _, car := range owners {
+			car.StreamFiles = append(car.StreamFiles, streamFile.Path)
+		}
+	}
+
+	for _, audioFile := range audioFiles {
+		pack := AudioPackName(audioFile)
+		folderCars := byFolder[topLevelFolder(root, audioFile.Path)]
+		owners := onlyCar(folderCars)
+		if car := findAudioCar(folderCars, pack); car != nil {
+			owners = []*Car{car}
+		} else if car := findAudioCar(cars, pack); car != nil && len(owners) == 0 {
+			owners = []*Car{car}
+		}
+		if len(owners) == 0 {
+			unassigned = append(unassigned, audioFile.Path)
+		}
+		for _, car := range owners {
+			car.AudioFiles = append(car.AudioFiles, audioFile.Path)
+			if !sliceutils.ContainsElement(car.AudioPacks, pack) {
+				car.AudioPacks = append(car.AudioPacks, pack)
+			}
+		}
+	}
+
+	for _, car := range cars {
+		car.Missing = missingPieces(car)
+		sort.Strings(car.DataTypes)
+		sort.Strings(car.AudioPacks)
+	}
+	sort.SliceStable(cars, func(i, j int) bool {
+		if cars[i].Model != cars[j].Model {
+			return cars[i].Model < cars[j].Model
+		}
+		return cars[i].Folder < cars[j].Folder
+	})
+
+	return cars, unassigned
+}
+
+// AudioPackName returns the engine name of an audio config file, or the dlc folder of a wave pack.
+func AudioPackName(audioFile dft.AudioFile) string {
+	if !audioFile.IsConfig {
+		return strings.ToLower(audioFile.DLCFolder)
+	}
+	// Example: hondaf20c_game.dat151.rel -> hondaf20c
+	return strings.ToLower(strings.Split(audioFile.Name, "_")[0])
+}
+
+// StreamFileModel returns the model of cars whose stream files match name, preferring the longest model name.
+// Stream files are named after their model, e.g. adder.yft, adder_hi.yft and adder+hi.ytd.
+func StreamFileModel(models []string, name string) string {
+	base := strings.ToLower(name)
+	if i := strings.Index(base, "."); i >= 0 {
+		base = base[:i]
+	}
+
+	match := ""
+	for _, model := range models {
+		if len(model) <= len(match) {
+			continue
+		}
+		if base == model || strings.HasPrefix(base, model+"_") || strings.HasPrefix(base, model+"+") {
+			match = model
+		}
+	}
+	return match
+}
+
+func matchStreamFile(cars []*Car, name string) *Car {
+	var models []string
+	for _, car := range cars {
+		models = append(models, car.Model)
+	}
+	model := StreamFileModel(models, name)
+	if model == "" {
+		return nil
+	}
+	return findCar(cars, model)
+}
+
+func missingPieces(car *Car) []string {
+	var missing []string
+	hasModel := false
+	for _, streamFile := range car.StreamFiles {
+		if strings.EqualFold(filepath.Base(streamFile), car.Model+".yft") {
+			hasModel = true
+		}
+	}
+	if !hasModel {
+		missing = append(missing, car.Model+".yft")
+	}
+	if !sliceutils.ContainsElement(car.DataTypes, dft.VEHICLES.String()) {
+		missing = append(missing, "vehicles.meta")
+	}
+	if !sliceutils.ContainsElement(car.DataTypes, dft.HANDLING.String()) {
+		missing = append(missing, "handling.meta")
+	}
+	return missing
+}
+
+func findCar(cars []*Car, model string) *Car {
+	for _, car := range cars {
+		if car.Model == model {
+			return car
+		}
+	}
+	return nil
+}
+
+func findAudioCar(cars []*Car, pack string) *Car {
+	for _, car := range cars {
+		if car.AudioName != "" && car.AudioName == pack {
+			return car
+		}
+	}
+	return nil
+}
+
+// onlyCar returns the car of a folder when the folder holds exactly one.
+func onlyCar(cars []*Car) []*Car {
+	if len(cars) == 1 {
+		return cars
+	}
+	return nil
+}
+
+func topLevelFolder(root string, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return "."
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 2 {
+		return "."
+	}
+	return parts[0]
+}
@@ -0,0 +1,151 @@
+package carfinder
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/dft"
+	sliceutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/slice"
+)
+
+// ResourceCars groups the files of a resource written by the merger by car.
+// Data files are named <type>_<model>.meta after the vehicle the copier found for them,
+// so the model is read back from the file name. Stream files are matched against every
+// model declared in the vehicles.meta files and audio files by the vehicle's audioNameHash.
+func ResourceCars(resourcePath string) ([]*Car, []string, error) {
+	var cars []*Car
+	var unassigned []string
+	owners := make(map[string]*Car)
+
+	dataPath := filepath.Join(resourcePath, "data")
+	typeDirs, err := os.ReadDir(dataPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	carFor := func(model string) *Car {
+		if car := findCar(cars, model); car != nil {
+			return car
+		}
+		car := &Car{Model: model}
+		cars = append(cars, car)
+		return car
+	}
+
+	for _, typeDir := range typeDirs {
+		if !typeDir.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(dataPath, typeDir.Name()))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, file := range files {
+			path := filepath.Join(dataPath, typeDir.Name(), file.Name())
+			model, ok := DataFileModel(typeDir.Name(), file.Name())
+			if !ok {
+				unassigned = append(unassigned, path)
+				continue
+			}
+
+			car := carFor(model)
+			car.DataFiles = append(car.DataFiles, path)
+			if !sliceutils.ContainsElement(car.DataTypes, strings.ToUpper(typeDir.Name())) {
+				car.DataTypes = append(car.DataTypes, strings.ToUpper(typeDir.Name()))
+			}
+			owners[model] = car
+
+			if strings.EqualFold(typeDir.Name(), dft.VEHICLES.String()) {
+				vehicles, err := ReadVehicles(path)
+				if err != nil {
+					return nil, nil, err
+				}
+				for _, vehicle := range vehicles {
+					if _, ok := owners[strings.ToLower(vehicle.ModelName)]; !ok {
+						owners[strings.ToLower(vehicle.ModelName)] = car
+					}
+					if vehicle.AudioNameHash != "" && car.AudioName == "" {
+						car.AudioName = strings.ToLower(vehicle.AudioNameHash)
+					}
+				}
+			}
+		}
+	}
+
+	var models []string
+	for model := range owners {
+		models = append(models, model)
+	}
+
+	streamFiles, _ := os.ReadDir(filepath.Join(resourcePath, "stream"))
+	for _, file := range streamFiles {
+		path := filepath.Join(resourcePath, "stream", file.Name())
+		model := StreamFileModel(models, file.Name())
+		if model == "" {
+			if streamModel, ok := StreamFileCar(file.Name()); ok {
+				car := carFor(streamModel)
+				car.StreamFiles = append(car.StreamFiles, path)
+				continue
+			}
+			unassigned = append(unassigned, path)
+			continue
+		}
+		owners[model].StreamFiles = append(owners[model].StreamFiles, path)
+	}
+
+	audioConfigs, _ := os.ReadDir(filepath.Join(resourcePath, "audioconfig"))
+	for _, file := range audioConfigs {
+		path := filepath.Join(resourcePath, "audioconfig", file.Name())
+		if !addAudioFile(cars, path, AudioPackName(dft.AudioFile{Name: file.Name(), IsConfig: true})) {
+			unassigned = append(unassigned, path)
+		}
+	}
+
+	packs, _ := os.ReadDir(filepath.Join(resourcePath, "sfx"))
+	for _, pack := range packs {
+		packPath := filepath.Join(resourcePath, "sfx", pack.Name())
+		packFiles, _ := os.ReadDir(packPath)
+		for _, file := range packFiles {
+			path := filepath.Join(packPath, file.Name())
+			if !addAudioFile(cars, path, strings.ToLower(strings.TrimPrefix(pack.Name(), "dlc_"))) {
+				unassigned = append(unassigned, path)
+			}
+		}
+	}
+
+	for _, car := range cars {
+		car.Missing = missingPieces(car)
+		sort.Strings(car.DataTypes)
+		sort.Strings(car.AudioPacks)
+	}
+	sort.SliceStable(cars, func(i, j int) bool {
+		return cars[i].Model < cars[j].Model
+	})
+
+	return cars, unassigned, nil
+}
+
+// DataFileModel returns the model of a data file named <type>_<model>.meta by the copier.
+func DataFileModel(typeDir string, name string) (string, bool) {
+	prefix := strings.ToLower(typeDir) + "_"
+	lower := strings.ToLower(name)
+	if !strings.HasPrefix(lower, prefix) || !strings.HasSuffix(lower, ".meta") {
+		return "", false
+	}
+	model := strings.TrimSuffix(strings.TrimPrefix(lower, prefix), ".meta")
+	return model, model != ""
+}
+
+func addAudioFile(cars []*Car, path string, pack string) bool {
+	car := findAudioCar(cars, pack)
+	if car == nil {
+		return false
+	}
+	car.AudioFiles = append(car.AudioFiles, path)
+	if !sliceutils.ContainsElement(car.AudioPacks, pack) {
+		car.AudioPacks = append(car.AudioPacks, pack)
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package carfinder
+
+import (
+	"encoding/xml"
+	"os"
+	"strings"
+)
+
+// Vehicle holds the identifying fields of one vehicles.meta entry.
+type Vehicle struct {
+	ModelName     string `xml:"modelName"`
+	TxdName       string `xml:"txdName"`
+	HandlingID    string `xml:"handlingId"`
+	GameName      string `xml:"gameName"`
+	AudioNameHash string `xml:"audioNameHash"`
+}
+
+type initDataList struct {
+	Items []Vehicle `xml:"InitDatas>Item"`
+}
+
+// ReadVehicles parses every vehicle declared in a vehicles.meta.
+func ReadVehicles(path string) ([]Vehicle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	list := initDataList{}
+	if err := xml.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	var vehicles []Vehicle
+	for _, vehicle := range list.Items {
+		vehicle.ModelName = strings.TrimSpace(vehicle.ModelName)
+		if vehicle.ModelName == "" {
+			continue
+		}
+		vehicle.TxdName = strings.TrimSpace(vehicle.TxdName)
+		vehicle.HandlingID = strings.TrimSpace(vehicle.HandlingID)
+		vehicle.GameName = strings.TrimSpace(vehicle.GameName)
+		vehicle.AudioNameHash = strings.TrimSpace(vehicle.AudioNameHash)
+		vehicles = append(vehicles, vehicle)
+	}
+	return vehicles, nil
+}
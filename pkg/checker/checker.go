@@ -0,0 +1,228 @@
+package checker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/carfinder"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/dft"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/ignore"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/manifestgen"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/typeidentifier"
+	sliceutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/slice"
+)
+
+type Severity string
+
+const (
+	ERROR   Severity = "error"
+	WARNING Severity = "warning"
+)
+
+type Problem struct {
+	Severity Severity
+	Path     string
+	Message  string
+}
+
+type Result struct {
+	ResourcePath string
+	Cars         []string
+	Problems     []Problem
+}
+
+func (r *Result) Errors() int {
+	return r.count(ERROR)
+}
+
+func (r *Result) Warnings() int {
+	return r.count(WARNING)
+}
+
+func (r *Result) count(severity Severity) int {
+	count := 0
+	for _, problem := range r.Problems {
+		if problem.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *Result) add(severity Severity, path string, format string, args ...any) {
+	r.Problems = append(r.Problems, Problem{Severity: severity, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+type Checker interface {
+	Check(resourcePath string) (*Result, error)
+}
+
+type checker struct {
+	Flags          flags.Flags
+	TypeIdentifier typeidentifier.TypeIdentifier
+}
+
+func New(_flags flags.Flags) Checker {
+	return &checker{
+		Flags:          _flags,
+		TypeIdentifier: typeidentifier.New(),
+	}
+}
+
+// Check validates an existing resource without changing it. Problems found in the
+// resource are part of the result, the error is only set when the checks cannot run.
+func (c *checker) Check(resourcePath string) (*Result, error) {
+	if info, err := os.Stat(resourcePath); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", resourcePath)
+	}
+
+	result := &Result{ResourcePath: resourcePath}
+	c.checkManifest(result)
+	if err := c.checkDataFiles(result); err != nil {
+		return nil, err
+	}
+	c.checkCars(result)
+
+	return result, nil
+}
+
+// audioDataFiles are the data_file types whose path leaves off the <version>.rel of the
+// file, e.g. audioconfig/v8a_game.dat loads audioconfig/v8a_game.dat151.rel.
+var audioDataFiles = []string{"AUDIO_GAMEDATA", "AUDIO_SOUNDDATA", "AUDIO_SYNTHDATA", "AUDIO_DYNAMIXDATA"}
+
+func (c *checker) checkManifest(result *Result) {
+	manifestPath := filepath.Join(result.ResourcePath, "fxmanifest.lua")
+	manifest, err := manifestgen.ParseManifest(manifestPath)
+	if err != nil {
+		result.add(ERROR, manifestPath, "cannot read manifest: %v", err)
+		return
+	}
+
+	files, dirs, err := resourcePaths(result.ResourcePath)
+	if err != nil {
+		result.add(ERROR, result.ResourcePath, "cannot list resource files: %v", err)
+		return
+	}
+
+	for _, entry := range manifest.Files {
+		if !matches(entry, "", files) {
+			result.add(ERROR, manifestPath, "files entry %s matches no files", entry)
+		}
+	}
+	for _, entry := range manifest.DataFiles {
+		found := false
+		switch {
+		case strings.EqualFold(entry.Type, "AUDIO_WAVEPACK"):
+			found = matches(entry.Path, "", dirs)
+		case sliceutils.ContainsElement(audioDataFiles, entry.Type):
+			found = matches(entry.Path, `[0-9]+\.rel`, files)
+		default:
+			found = matches(entry.Path, "", files)
+		}
+		if !found {
+			result.add(ERROR, manifestPath, "data_file %s %s matches no files", entry.Type, entry.Path)
+		}
+	}
+}
+
+// resourcePaths returns the slash separated paths of every file and folder of a resource.
+func resourcePaths(resourcePath string) ([]string, []string, error) {
+	var files, dirs []string
+	err := filepath.Walk(resourcePath, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(resourcePath, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if f.IsDir() {
+			dirs = append(dirs, filepath.ToSlash(rel))
+		} else {
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files, dirs, err
+}
+
+// matches reports whether a manifest pattern, followed by the regular expression suffix,
+// matches one of paths exactly. Patterns are globs like FiveM reads them, ** included.
+func matches(pattern string, suffix string, paths []string) bool {
+	pattern = strings.TrimPrefix(strings.TrimSpace(filepath.ToSlash(pattern)), "./")
+	regex, err := regexp.Compile("^" + ignore.GlobToRegex(pattern) + suffix + "$")
+	if err != nil {
+		return false
+	}
+	for _, path := range paths {
+		if regex.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *checker) checkDataFiles(result *Result) error {
+	dataPath := filepath.Join(result.ResourcePath, "data")
+	if _, err := os.Stat(dataPath); os.IsNotExist(err) {
+		result.add(ERROR, dataPath, "resource has no data folder")
+		return nil
+	}
+
+	return filepath.Walk(dataPath, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".meta") {
+			return nil
+		}
+
+		_type, err := c.TypeIdentifier.IdentifyDataFileType(path)
+		if err != nil {
+			result.add(ERROR, path, "cannot parse meta file: %v", err)
+			return nil
+		}
+		if _type == dft.INVALID {
+			result.add(ERROR, path, "not a known data file type")
+			return nil
+		}
+
+		folder := filepath.Base(filepath.Dir(path))
+		if !strings.EqualFold(folder, _type.String()) {
+			result.add(ERROR, path, "%s file is in data/%s, expected data/%s", _type.String(), folder, strings.ToLower(_type.String()))
+		}
+		return nil
+	})
+}
+
+func (c *checker) checkCars(result *Result) {
+	resourceFlags := c.Flags
+	resourceFlags.OutputPath = result.ResourcePath
+	carFinder := carfinder.New(resourceFlags)
+
+	dataFileCars, err := carFinder.FindDataFileCars()
+	if err != nil {
+		result.add(ERROR, result.ResourcePath, "cannot read vehicles data: %v", err)
+		return
+	}
+	streamFileCars, err := carFinder.FindStreamFileCars()
+	if err != nil {
+		result.add(ERROR, result.ResourcePath, "cannot read stream files: %v", err)
+		return
+	}
+
+	validCars, noStreamCars, noDataCars := carfinder.ClassifyCars(dataFileCars, streamFileCars)
+	result.Cars = sliceutils.RemoveDuplicates(validCars)
+	for _, car := range noStreamCars {
+		result.add(ERROR, filepath.Join(result.ResourcePath, "stream"), "car %s has no %s.yft", car, car)
+	}
+	for _, car := range noDataCars {
+		result.add(WARNING, filepath.Join(result.ResourcePath, "data", "vehicles"), "stream file %s.yft has no vehicles.meta entry", car)
+	}
+}
@@ -0,0 +1,95 @@
+package checker
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+)
+
+func TestMatches(t *testing.T) {
+	paths := []string{
+		"data/handling/handling_adder.meta",
+		"stream/adder.yft",
+		"stream/textures/adder.ytd",
+		"audioconfig/v8a_game.dat151.rel",
+	}
+	tests := []struct {
+		pattern string
+		suffix  string
+		want    bool
+	}{
+		{pattern: "data/handling/*.meta", want: true},
+		{pattern: "./data/handling/*.meta", want: true},
+		{pattern: "data/*.meta", want: false},
+		{pattern: "data/**/*.meta", want: true},
+		{pattern: "stream/**/*.ytd", want: true},
+		{pattern: "stream/**.ytd", want: true},
+		{pattern: "stream/*.ytd", want: false},
+		{pattern: "stream/adder.yft", want: true},
+		{pattern: "stream/adder", want: false},
+		{pattern: "stream/add", want: false},
+		{pattern: "audioconfig/v8a_game.dat", want: false},
+		{pattern: "audioconfig/v8a_game.dat", suffix: `[0-9]+\.rel`, want: true},
+		{pattern: "audioconfig/v8a_game.da", suffix: `[0-9]+\.rel`, want: false},
+		{pattern: "audioconfig/v8a_sounds.dat", suffix: `[0-9]+\.rel`, want: false},
+	}
+	for _, test := range tests {
+		if got := matches(test.pattern, test.suffix, paths); got != test.want {
+			t.Errorf("matches(%q, %q) = %v, want %v", test.pattern, test.suffix, got, test.want)
+		}
+	}
+}
+
+func TestCheckManifest(t *testing.T) {
+	resource := t.TempDir()
+	for _, path := range []string{
+		"data/handling/handling_adder.meta",
+		"stream/textures/adder.ytd",
+		"audioconfig/v8a_game.dat151.rel",
+		"sfx/dlc_v8a/v8a.awc",
+	} {
+		full := filepath.Join(resource, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifest := `fx_version 'cerulean'
+files {
+    'data/handling/*.meta',
+    'stream/**/*.ytd',
+    'data/vehicles/*.meta',
+    'sfx/dlc_v8a/*.awc',
+}
+data_file 'HANDLING_FILE' 'data/handling/*.meta'
+data_file 'VEHICLE_VARIATION_FILE' 'data/vehiclevariations/*.meta'
+data_file 'AUDIO_GAMEDATA' 'audioconfig/v8a_game.dat'
+data_file 'AUDIO_SOUNDDATA' 'audioconfig/v8a_sounds.dat'
+data_file 'AUDIO_WAVEPACK' 'sfx/dlc_v8a'
+data_file 'AUDIO_WAVEPACK' 'sfx/dlc_v8'
+`
+	if err := os.WriteFile(filepath.Join(resource, "fxmanifest.lua"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := &Result{ResourcePath: resource}
+	New(flags.Flags{}).(*checker).checkManifest(result)
+	want := []string{
+		"files entry data/vehicles/*.meta matches no files",
+		"data_file VEHICLE_VARIATION_FILE data/vehiclevariations/*.meta matches no files",
+		"data_file AUDIO_SOUNDDATA audioconfig/v8a_sounds.dat matches no files",
+		"data_file AUDIO_WAVEPACK sfx/dlc_v8 matches no files",
+	}
+	var got []string
+	for _, problem := range result.Problems {
+		got = append(got, problem.Message)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/config"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/exitcode"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/hash"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/logger"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/overrides"
+	"github.com/charmbracelet/log"
+	"github.com/spf13/pflag"
+)
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string) error
+}
+
+func commands() []command {
+	return []command{
+		{name: "merge", usage: "merge [flags]", summary: "Merge every car in the input path into one resource", run: runMerge},
+		{name: "add", usage: "add <car-folder>", summary: "Add one car to an existing merged resource", run: runAdd},
+		{name: "remove", usage: "remove <model>", summary: "Remove one car from an existing merged resource", run: runRemove},
+		{name: "inspect", usage: "inspect [input]", summary: "List the cars in an input path without writing anything", run: runInspect},
+		{name: "validate", usage: "validate [resource]", summary: "Check an existing merged resource without re-merging it", run: runValidate},
+		{name: "diff", usage: "diff <old> <new>", summary: "Compare two merged resources car by car", run: runDiff},
+		{name: "split", usage: "split [resource]", summary: "Write one standalone resource per car of a merged resource", run: runSplit},
+		{name: "watch", usage: "watch [flags]", summary: "Re-merge whenever the input path changes", run: runWatch},
+		{name: "serve", usage: "serve [--addr]", summary: "Run a local HTTP API to start and monitor merges", run: runServe},
+		{name: "extract", usage: "extract <file.rpf>", summary: "Extract the files of an RPF archive", run: runExtract},
+		{name: "rpf", usage: "rpf ls|pack", summary: "List an RPF archive without extracting it, or pack a resource into one", run: runRPF},
+		{name: "hash", usage: "hash <name>|lookup <hash>", summary: "Print the joaat hash of names, or look up which car a hash belongs to", run: runHash},
+		{name: "config", usage: "config [flags]", summary: "Print the effective settings and where each one comes from", run: runConfig},
+		{name: "plan", usage: "plan [flags]", summary: "Write a plan of what merge would do without copying anything", run: runPlan},
+		{name: "apply", usage: "apply [plan-file]", summary: "Run a plan written by the plan command", run: runApply},
+	}
+}
+
+// Run executes the subcommand named by args[0] and returns the process exit code.
+func Run(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	for _, cmd := range commands() {
+		if cmd.name != args[0] {
+			continue
+		}
+		err := cmd.run(args[1:])
+		var warnings *exitcode.WarningsError
+		var paths *config.PathsError
+		switch {
+		case err == nil:
+		case errors.Is(err, pflag.ErrHelp):
+			return exitcode.Success
+		case errors.As(err, &warnings):
+			log.Warn(cmd.name + " " + err.Error())
+		case errors.As(err, &paths):
+			log.Error(cmd.name + " failed, fix these settings first")
+			for _, problem := range paths.Problems {
+				log.Error("  " + problem)
+			}
+		default:
+			log.Error(cmd.name+" failed", "err", err)
+		}
+		return exitcode.FromError(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	printUsage(os.Stderr)
+	return exitcode.Usage
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: FiveMCarsMerger [command] [flags]")
+	fmt.Fprintln(w, "\nRun without a command to open the interactive menu.")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-24s %s\n", cmd.usage, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nRun 'FiveMCarsMerger <command> --help' for the flags of a command.")
+	fmt.Fprintln(w, "\nExit codes:")
+	fmt.Fprintf(w, "  %-3d success\n", exitcode.Success)
+	fmt.Fprintf(w, "  %-3d failure\n", exitcode.Failure)
+	fmt.Fprintf(w, "  %-3d success with warnings\n", exitcode.Warnings)
+	fmt.Fprintf(w, "  %-3d validation failure\n", exitcode.Validation)
+	fmt.Fprintf(w, "  %-3d I/O failure\n", exitcode.IO)
+	fmt.Fprintf(w, "  %-3d invalid usage\n", exitcode.Usage)
+}
+
+func newFlagSet(name string) *pflag.FlagSet {
+	fs := pflag.NewFlagSet(name, pflag.ContinueOnError)
+	fs.SortFlags = false
+	return fs
+}
+
+// parseFlags loads config.json, binds the shared flags on top of it and parses args.
+// Flags given on the command line override the values from the config file.
+func parseFlags(fs *pflag.FlagSet, args []string) (*flags.Flags, error) {
+	appFlags, _, _, err := loadFlags(fs, args)
+	return appFlags, err
+}
+
+// loadFlags is parseFlags that also returns the profile the settings were read from, empty
+// without a config file, and where each setting came from. Settings are layered from lowest
+// to highest priority: the config file, FIVEMMERGER_* environment variables and command line
+// flags.
+func loadFlags(fs *pflag.FlagSet, args []string) (*flags.Flags, string, config.Sources, error) {
+	// The config file and profile decide the defaults of every other flag, so they are read before parsing
+	configPath := flagArg(args, "config")
+	fs.String("config", configPath, "config file to use instead of looking for config.json")
+	if configPath != "" {
+		config.SetPath(absPath(configPath))
+	}
+	hash.DictionaryPath = filepath.Join(filepath.Dir(config.Path()), hash.DictionaryFile)
+	overrides.DefaultPath = filepath.Join(filepath.Dir(config.Path()), overrides.FileName)
+
+	profile := flagArg(args, "profile")
+	fs.String("profile", profile, "profile of config.json to use (default the active profile)")
+	if profile == "" {
+		profile = os.Getenv(config.EnvPrefix + "PROFILE")
+	}
+
+	appFlags := &flags.Flags{}
+	sources := config.Sources{}
+	sources.SetAll("default")
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg != nil {
+		profileFlags, err := cfg.Profile(profile)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("%w: %v", exitcode.ErrUsage, err)
+		}
+		*appFlags = *profileFlags
+		appFlags.InputPath = config.ResolvePath(appFlags.InputPath)
+		appFlags.ExtraInputs = config.ResolvePaths(appFlags.ExtraInputs)
+		appFlags.OutputPath = config.ResolvePath(appFlags.OutputPath)
+		appFlags.Overrides = config.ResolvePath(appFlags.Overrides)
+		if profile == "" {
+			profile = cfg.ActiveProfile
+		}
+		sources.SetAll(fmt.Sprintf("file %s (profile %s)", config.Path(), profile))
+	} else if profile != "" {
+		return nil, "", nil, exitcode.Usagef("profile %q requested, but there is no config file at %s", profile, config.Path())
+	}
+	if err := config.ApplyEnv(appFlags, sources); err != nil {
+		return nil, "", nil, fmt.Errorf("%w: %v", exitcode.ErrUsage, err)
+	}
+
+	fs.StringVarP(&appFlags.InputPath, "input", "i", appFlags.InputPath, "path to all cars")
+	fs.StringSliceVar(&appFlags.ExtraInputs, "extra-input", appFlags.ExtraInputs, "more paths to cars, with a lower priority than --input and the ones before them")
+	fs.StringVarP(&appFlags.OutputPath, "output", "o", appFlags.OutputPath, "output path for merged cars")
+	fs.BoolVar(&appFlags.Clean, "clean", appFlags.Clean, "clean the output directory before merging")
+	fs.BoolVarP(&appFlags.Verbose, "verbose", "v", appFlags.Verbose, "enable verbose logging")
+	fs.BoolVar(&appFlags.Strict, "strict", appFlags.Strict, "treat missing stream or data files, unknown metas and conflicts as errors")
+	fs.StringSliceVar(&appFlags.Include, "include", appFlags.Include, "only merge files matching these gitignore-style patterns")
+	fs.StringSliceVar(&appFlags.Exclude, "exclude", appFlags.Exclude, "never merge files matching these gitignore-style patterns")
+	fs.BoolVar(&appFlags.Rebuild, "rebuild", false, "ignore the build cache and rebuild the output from scratch")
+	fs.IntVarP(&appFlags.Concurrency, "concurrency", "j", appFlags.Concurrency, "files walked, identified and copied at once (default the number of CPUs)")
+	fs.StringVar(&appFlags.Overrides, "overrides", appFlags.Overrides, "per-car overrides file (default overrides.json next to the config)")
+
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return nil, "", nil, err
+		}
+		return nil, "", nil, fmt.Errorf("%w: %v", exitcode.ErrUsage, err)
+	}
+	for _, setting := range config.Settings {
+		if setting.Flag != "" && fs.Changed(setting.Flag) {
+			sources[setting.Name] = "flag --" + setting.Flag
+		}
+	}
+
+	logger.Configure(appFlags.Verbose)
+
+	appFlags.OutputPath = absPath(appFlags.OutputPath)
+	appFlags.InputPath = absPath(appFlags.InputPath)
+	for i, input := range appFlags.ExtraInputs {
+		appFlags.ExtraInputs[i] = absPath(input)
+	}
+	appFlags.Overrides = absPath(appFlags.Overrides)
+
+	return appFlags, profile, sources, nil
+}
+
+// flagArg returns the value of the flag --name in args, or an empty string when it isn't set.
+func flagArg(args []string, name string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--"+name+"="); ok {
+			return value
+		}
+		if arg == "--"+name && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+func absPath(path string) string {
+	if path == "" {
+		return path
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+func requirePaths(appFlags *flags.Flags, names ...string) error {
+	var missing []string
+	for _, name := range names {
+		switch {
+		case name == "input" && appFlags.InputPath == "":
+			missing = append(missing, "--input")
+		case name == "output" && appFlags.OutputPath == "":
+			missing = append(missing, "--output")
+		}
+	}
+	if len(missing) > 0 {
+		return exitcode.Usagef("missing %s (set it in config.json or pass it as a flag)", strings.Join(missing, " and "))
+	}
+	return nil
+}
+
+// warningsError reports a successful command that produced warnings.
+func warningsError(count int) error {
+	if count == 0 {
+		return nil
+	}
+	return &exitcode.WarningsError{Count: count}
+}
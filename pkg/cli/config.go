@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/config"
+)
+
+// runConfig prints the effective settings and the layer each of them came from.
+func runConfig(args []string) error {
+	fs := newFlagSet("config")
+	appFlags, _, sources, err := loadFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	configPath := config.Path()
+	state := "found"
+	if _, err := os.Stat(configPath); err != nil {
+		state = "not found"
+	}
+	fmt.Printf("Config file: %s (%s)\n\n", configPath, state)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+	for _, setting := range config.Settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", setting.Name, orDash(setting.Value(appFlags)), sources[setting.Name])
+	}
+	return tw.Flush()
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/differ"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/exitcode"
+)
+
+func runDiff(args []string) error {
+	fs := newFlagSet("diff")
+	format := fs.StringP("format", "f", "text", "output format, text, markdown or json")
+	appFlags, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return exitcode.Usagef("diff needs an old and a new resource, got %d arguments", fs.NArg())
+	}
+
+	changes, err := differ.New(*appFlags).Diff(absPath(fs.Arg(0)), absPath(fs.Arg(1)))
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "text":
+		return changes.WriteText(os.Stdout)
+	case "markdown":
+		return changes.WriteMarkdown(os.Stdout)
+	case "json":
+		return changes.WriteJSON(os.Stdout)
+	default:
+		return exitcode.Usagef("unknown format %q, expected text, markdown or json", *format)
+	}
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/exitcode"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/merger"
+)
+
+func runAdd(args []string) error {
+	fs := newFlagSet("add")
+	appFlags, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return exitcode.Usagef("add needs exactly one car folder, got %d", fs.NArg())
+	}
+	if err := requirePaths(appFlags, "output"); err != nil {
+		return err
+	}
+
+	return merger.New(*appFlags).Add(absPath(fs.Arg(0)))
+}
+
+func runRemove(args []string) error {
+	fs := newFlagSet("remove")
+	appFlags, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return exitcode.Usagef("remove needs exactly one model name, got %d", fs.NArg())
+	}
+	if err := requirePaths(appFlags, "output"); err != nil {
+		return err
+	}
+
+	return merger.New(*appFlags).Remove(fs.Arg(0))
+}
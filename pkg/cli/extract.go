@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/exitcode"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/hash"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/progressbar"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/rpf"
+	"github.com/charmbracelet/log"
+)
+
+func runExtract(args []string) error {
+	fs := newFlagSet("extract")
+	out := fs.String("out", "", "where to extract the archive to (default the archive path without .rpf)")
+	only := fs.StringSlice("only", nil, "only extract these extensions, e.g. --only .yft,.ytd,.meta")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return exitcode.Usagef("extract takes one archive, got %d", fs.NArg())
+	}
+
+	archive := absPath(fs.Arg(0))
+	destination := *out
+	if destination == "" {
+		destination = strings.TrimSuffix(archive, filepath.Ext(archive))
+	}
+
+	bar := progressbar.New(os.Stderr, "Extracting")
+	extractor := &rpf.Extractor{
+		CachePath:  absPath(destination),
+		Extensions: *only,
+		OnProgress: bar.Update,
+	}
+	dictionary, dictionaryErr := hash.LoadDictionary(hash.DictionaryPath)
+	if dictionaryErr == nil {
+		extractor.Dictionary = dictionary
+	}
+	err := extractor.Extract(archive)
+	bar.Finish()
+	if err != nil {
+		return err
+	}
+	if dictionaryErr == nil {
+		dictionaryErr = dictionary.Save(hash.DictionaryPath)
+	}
+	if dictionaryErr != nil {
+		log.Warn("Failed to update the hash dictionary", "path", hash.DictionaryPath, "err", dictionaryErr)
+	}
+
+	extractor.LogSummary()
+	return nil
+}
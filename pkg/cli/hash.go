@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/exitcode"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/hash"
+)
+
+// runHash prints the joaat hash of every name, or with lookup the names a hash belongs to.
+func runHash(args []string) error {
+	if len(args) > 0 && args[0] == "lookup" {
+		return runHashLookup(args[1:])
+	}
+
+	fs := newFlagSet("hash")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return exitcode.Usagef("hash needs at least one name")
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tHEX\tUNSIGNED\tSIGNED")
+	for _, name := range fs.Args() {
+		h := hash.Joaat(name)
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\n", name, hash.Format(h), h, int32(h))
+	}
+	return tw.Flush()
+}
+
+func runHashLookup(args []string) error {
+	// Signed hashes from server logs would be read as shorthand flags
+	args = slices.Clone(args)
+	for i, arg := range args {
+		if h, err := hash.Parse(arg); err == nil && strings.HasPrefix(arg, "-") {
+			args[i] = hash.Format(h)
+		}
+	}
+
+	fs := newFlagSet("hash lookup")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return exitcode.Usagef("hash lookup needs at least one hash")
+	}
+
+	dictionary, err := hash.LoadDictionary(hash.DictionaryPath)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HASH\tNAME\tKIND\tCAR")
+	for _, value := range fs.Args() {
+		h, err := hash.Parse(value)
+		if err != nil {
+			return exitcode.Usagef("%v", err)
+		}
+		entries := dictionary.Lookup(h)
+		if len(entries) == 0 {
+			fmt.Fprintf(tw, "%s\t-\tunknown\t-\n", hash.Format(h))
+		}
+		for _, entry := range entries {
+			car := entry.Car
+			if car == "" {
+				car = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", hash.Format(h), entry.Name, entry.Kind, car)
+		}
+	}
+	return tw.Flush()
+}
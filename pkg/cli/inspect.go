@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/exitcode"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/inspector"
+)
+
+func runInspect(args []string) error {
+	fs := newFlagSet("inspect")
+	format := fs.StringP("format", "f", "table", "output format, table or json")
+	appFlags, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		appFlags.InputPath = absPath(fs.Arg(0))
+	}
+	if err := requirePaths(appFlags, "input"); err != nil {
+		return err
+	}
+
+	inventory, err := inspector.New(*appFlags).Inspect()
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		return inventory.WriteJSON(os.Stdout)
+	case "table":
+		return inventory.WriteTable(os.Stdout)
+	default:
+		return exitcode.Usagef("unknown format %q, expected table or json", *format)
+	}
+}
@@ -0,0 +1,22 @@
+package cli
+
+import (
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/merger"
+)
+
+func runMerge(args []string) error {
+	fs := newFlagSet("merge")
+	appFlags, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requirePaths(appFlags, "input", "output"); err != nil {
+		return err
+	}
+
+	carsMerger := merger.New(*appFlags)
+	if err := carsMerger.Merge(); err != nil {
+		return err
+	}
+	return warningsError(len(carsMerger.Report().Warnings))
+}
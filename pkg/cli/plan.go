@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/exitcode"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/merger"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/plan"
+	"github.com/charmbracelet/log"
+)
+
+func runPlan(args []string) error {
+	fs := newFlagSet("plan")
+	planPath := fs.StringP("plan-file", "p", "", "where to write the plan (default <output>.plan.json next to the output)")
+	appFlags, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := requirePaths(appFlags, "input", "output"); err != nil {
+		return err
+	}
+
+	if *planPath == "" {
+		*planPath = plan.PathFor(appFlags.OutputPath)
+	}
+
+	p, err := merger.New(*appFlags).Plan()
+	if err != nil {
+		return err
+	}
+	for _, warning := range p.Warnings {
+		log.Warn(warning)
+	}
+	for _, conflict := range p.Conflicts {
+		log.Warn("Multiple files share one destination, the last one wins", "destination", conflict.Destination, "sources", conflict.Sources)
+	}
+	if len(p.NoStreamCars) > 0 {
+		log.Warn("Following cars have no stream files", "cars", p.NoStreamCars)
+	}
+	if len(p.NoDataCars) > 0 {
+		log.Warn("Following cars have no data files", "cars", p.NoDataCars)
+	}
+
+	if err := plan.Save(p, *planPath); err != nil {
+		return err
+	}
+
+	log.Info("Plan written", "path", *planPath, "operations", len(p.Operations), "cars", len(p.Cars), "conflicts", len(p.Conflicts))
+	return nil
+}
+
+func runApply(args []string) error {
+	fs := newFlagSet("apply")
+	appFlags, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() > 1 {
+		return exitcode.Usagef("apply takes at most one plan file, got %d", fs.NArg())
+	}
+	var planPath string
+	if fs.NArg() == 1 {
+		planPath = fs.Arg(0)
+	} else {
+		if err := requirePaths(appFlags, "output"); err != nil {
+			return err
+		}
+		planPath = plan.PathFor(appFlags.OutputPath)
+	}
+
+	p, err := plan.Load(planPath)
+	if err != nil {
+		return err
+	}
+
+	// The plan decides where files come from and go to, not the config
+	appFlags.InputPath = p.InputPath
+	appFlags.ExtraInputs = p.ExtraInputs
+	appFlags.OutputPath = p.OutputPath
+	appFlags.Clean = p.Clean
+
+	carsMerger := merger.New(*appFlags)
+	if err := carsMerger.Apply(p); err != nil {
+		return err
+	}
+	return warningsError(len(carsMerger.Report().Warnings))
+}
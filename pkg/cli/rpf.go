@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/cache"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/exitcode"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/rpf"
+	"github.com/charmbracelet/log"
+)
+
+// runRPF dispatches the subcommands that work on RPF archives.
+func runRPF(args []string) error {
+	if len(args) == 0 {
+		return exitcode.Usagef("rpf needs a subcommand: ls or pack")
+	}
+
+	switch args[0] {
+	case "ls":
+		return runRPFList(args[1:])
+	case "pack":
+		return runRPFPack(args[1:])
+	default:
+		return exitcode.Usagef("unknown rpf subcommand %q, expected ls or pack", args[0])
+	}
+}
+
+func runRPFList(args []string) error {
+	fs := newFlagSet("rpf ls")
+	format := fs.StringP("format", "f", "table", "output format, table or json")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return exitcode.Usagef("rpf ls takes one archive, got %d", fs.NArg())
+	}
+
+	tree, err := rpf.ReadTree(absPath(fs.Arg(0)))
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		return tree.WriteJSON(os.Stdout)
+	case "table":
+		return tree.WriteTable(os.Stdout)
+	default:
+		return exitcode.Usagef("unknown format %q, expected table or json", *format)
+	}
+}
+
+func runRPFPack(args []string) error {
+	fs := newFlagSet("rpf pack")
+	out := fs.String("out", "", "archive to write (default dlc.rpf next to the resource)")
+	compress := fs.Bool("compress", false, "zlib compress files when that makes them smaller")
+	dlcName := fs.String("dlc-name", "", "name of the DLC in content.xml (default the resource folder name)")
+	appFlags, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	resourcePath := appFlags.OutputPath
+	if fs.NArg() > 1 {
+		return exitcode.Usagef("rpf pack takes at most one resource, got %d", fs.NArg())
+	}
+	if fs.NArg() == 1 {
+		resourcePath = absPath(fs.Arg(0))
+	}
+	if resourcePath == "" {
+		return exitcode.Usagef("missing resource (pass it as an argument or set --output)")
+	}
+
+	archive := *out
+	if archive == "" {
+		archive = filepath.Join(filepath.Dir(resourcePath), "dlc.rpf")
+	}
+	name := *dlcName
+	if name == "" {
+		name = strings.ToLower(filepath.Base(resourcePath))
+	}
+
+	writer := &rpf.Writer{Compress: *compress}
+	if err := writer.AddDirectory(resourcePath); err != nil {
+		return err
+	}
+	writer.RemoveFile(cache.FileName)
+
+	// A merged resource has no content.xml, so it is made from the data files of its manifest
+	_, contentErr := os.Stat(filepath.Join(resourcePath, "content.xml"))
+	_, manifestErr := os.Stat(filepath.Join(resourcePath, "fxmanifest.lua"))
+	if os.IsNotExist(contentErr) && manifestErr == nil {
+		dataFiles, err := rpf.ContentDataFiles(resourcePath)
+		if err != nil {
+			return err
+		}
+		content, err := rpf.ContentXML(name, dataFiles)
+		if err != nil {
+			return err
+		}
+		writer.AddFile("content.xml", content)
+		log.Info("Generated content.xml", "dlc", name, "data_files", len(dataFiles))
+	}
+
+	if err := writer.Write(absPath(archive)); err != nil {
+		return err
+	}
+
+	log.Info("Packed resource", "archive", absPath(archive))
+	return nil
+}
@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/server"
+)
+
+func runServe(args []string) error {
+	fs := newFlagSet("serve")
+	addr := fs.String("addr", server.DefaultAddr, "address to listen on")
+	appFlags, profile, _, err := loadFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return server.New(*appFlags, profile).Serve(ctx, *addr)
+}
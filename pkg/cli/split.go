@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/exitcode"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/splitter"
+	"github.com/charmbracelet/log"
+)
+
+func runSplit(args []string) error {
+	fs := newFlagSet("split")
+	out := fs.String("out", "", "where to write the per-car resources (default <resource>-split)")
+	models := fs.StringSlice("car", nil, "only split out these models, may be repeated")
+	appFlags, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	resourcePath := appFlags.OutputPath
+	if fs.NArg() > 1 {
+		return exitcode.Usagef("split takes at most one resource, got %d", fs.NArg())
+	}
+	if fs.NArg() == 1 {
+		resourcePath = absPath(fs.Arg(0))
+	}
+	if resourcePath == "" {
+		return exitcode.Usagef("missing resource (pass it as an argument or set --output)")
+	}
+
+	destination := *out
+	if destination == "" {
+		destination = resourcePath + "-split"
+	}
+
+	written, err := splitter.New(*appFlags).Split(resourcePath, absPath(destination), *models)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Split resource", "cars", len(written), "output_folder", absPath(destination))
+	return nil
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/checker"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/exitcode"
+)
+
+func runValidate(args []string) error {
+	fs := newFlagSet("validate")
+	appFlags, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	resourcePath := appFlags.OutputPath
+	if fs.NArg() > 1 {
+		return exitcode.Usagef("validate takes at most one resource, got %d", fs.NArg())
+	}
+	if fs.NArg() == 1 {
+		resourcePath = absPath(fs.Arg(0))
+	}
+	if resourcePath == "" {
+		return exitcode.Usagef("missing resource (pass it as an argument or set --output)")
+	}
+
+	result, err := checker.New(*appFlags).Check(resourcePath)
+	if err != nil {
+		return err
+	}
+
+	for _, problem := range result.Problems {
+		fmt.Fprintf(os.Stdout, "%-7s %s: %s\n", problem.Severity, problem.Path, problem.Message)
+	}
+	fmt.Fprintf(os.Stdout, "%d cars, %d errors, %d warnings in %s\n", len(result.Cars), result.Errors(), result.Warnings(), resourcePath)
+
+	if result.Errors() > 0 || (appFlags.Strict && result.Warnings() > 0) {
+		return exitcode.Validationf("resource %s has %d errors and %d warnings", resourcePath, result.Errors(), result.Warnings())
+	}
+	return warningsError(result.Warnings())
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/config"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/exitcode"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/watcher"
+	"github.com/charmbracelet/log"
+)
+
+const (
+	watcherInterval = 2 * time.Second
+	watcherSettle   = 5 * time.Second
+)
+
+func runWatch(args []string) error {
+	fs := newFlagSet("watch")
+	interval := fs.Duration("interval", watcherInterval, "how often to poll the input path")
+	settle := fs.Duration("settle", watcherSettle, "how long changes must settle before merging")
+	appFlags, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return exitcode.Usagef("--interval must be positive, got %s", *interval)
+	}
+	if *settle < 0 {
+		return exitcode.Usagef("--settle can't be negative, got %s", *settle)
+	}
+	if err := requirePaths(appFlags, "input", "output"); err != nil {
+		return err
+	}
+	if err := config.CheckPaths(*appFlags); err != nil {
+		return err
+	}
+
+	// Every build replaces the previous one
+	if !appFlags.Clean {
+		log.Info("Watch mode always cleans the output directory before merging")
+		appFlags.Clean = true
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return watcher.New(*appFlags, *interval, *settle).Watch(ctx)
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/exitcode"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+)
+
+// PathsError lists every problem CheckPaths found.
+type PathsError struct {
+	Problems []string
+}
+
+func (e *PathsError) Error() string {
+	return "invalid paths: " + strings.Join(e.Problems, "; ")
+}
+
+func (e *PathsError) Unwrap() error {
+	return exitcode.ErrValidation
+}
+
+// CheckPaths makes sure a merge with f can't delete or overwrite anything it shouldn't,
+// since cleaning the output removes the whole folder. It returns a *PathsError with every
+// problem, or nil.
+func CheckPaths(f flags.Flags) error {
+	var problems []string
+
+	if f.InputPath == "" {
+		problems = append(problems, "input path is empty")
+	}
+	var inputs []string
+	for _, input := range f.InputRoots() {
+		info, err := os.Stat(input)
+		switch {
+		case err != nil:
+			problems = append(problems, "input path "+input+" does not exist")
+		case !info.IsDir():
+			problems = append(problems, "input path "+input+" is not a folder")
+		default:
+			inputs = append(inputs, input)
+		}
+	}
+
+	if f.OutputPath == "" {
+		problems = append(problems, "output path is empty")
+	} else {
+		problems = append(problems, checkOutput(realPath(f.OutputPath), f.OutputPath, inputs)...)
+	}
+
+	if len(problems) > 0 {
+		return &PathsError{Problems: problems}
+	}
+	return nil
+}
+
+func checkOutput(output string, name string, inputs []string) []string {
+	var problems []string
+
+	if filepath.Dir(output) == output {
+		problems = append(problems, "output path "+name+" is the root of the file system")
+	} else if info, err := os.Stat(output); err == nil && !info.IsDir() {
+		problems = append(problems, "output path "+name+" is not a folder")
+	} else if _, err := os.Stat(filepath.Dir(output)); err != nil {
+		problems = append(problems, "the folder "+filepath.Dir(name)+" of output path "+name+" does not exist")
+	}
+
+	if home, err := os.UserHomeDir(); err == nil && home != "" {
+		home = realPath(home)
+		homes := filepath.Dir(home)
+		switch {
+		case samePath(output, home):
+			problems = append(problems, "output path "+name+" is your home folder")
+		case contains(output, home):
+			problems = append(problems, "output path "+name+" contains your home folder "+home)
+		case filepath.Dir(homes) != homes && samePath(filepath.Dir(output), homes):
+			problems = append(problems, "output path "+name+" is a home folder")
+		}
+	}
+
+	for _, input := range inputs {
+		input = realPath(input)
+		switch {
+		case samePath(output, input):
+			problems = append(problems, "output path "+name+" is the input path")
+		case contains(output, input):
+			problems = append(problems, "output path "+name+" contains the input path "+input)
+		case contains(input, output):
+			problems = append(problems, "output path "+name+" is inside the input path "+input)
+		}
+	}
+	return problems
+}
+
+// realPath returns the absolute path with symlinks resolved, as far as the path exists.
+func realPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	if parent := filepath.Dir(abs); parent != abs {
+		return filepath.Join(realPath(parent), filepath.Base(abs))
+	}
+	return abs
+}
+
+func samePath(a string, b string) bool {
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// contains reports whether child is below parent.
+func contains(parent string, child string) bool {
+	if runtime.GOOS == "windows" {
+		parent, child = strings.ToLower(parent), strings.ToLower(child)
+	}
+	rel, err := filepath.Rel(parent, child)
+	if err != nil || rel == "." {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
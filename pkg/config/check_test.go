@@ -0,0 +1,108 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/exitcode"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+)
+
+func TestCheckPaths(t *testing.T) {
+	// Home folders live in <tmp>/home, so the checks don't depend on the machine
+	tmp, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	home := filepath.Join(tmp, "home", "me")
+	cars := filepath.Join(tmp, "cars")
+	donors := filepath.Join(tmp, "donors")
+	for _, dir := range []string{home, filepath.Join(tmp, "home", "other"), cars, donors, filepath.Join(tmp, "out")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file := filepath.Join(tmp, "file.txt")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	tests := []struct {
+		name  string
+		flags flags.Flags
+		want  []string // substrings of the problems, in order
+	}{
+		{name: "valid new output", flags: flags.Flags{InputPath: cars, OutputPath: filepath.Join(tmp, "merged")}},
+		{name: "valid existing output", flags: flags.Flags{InputPath: cars, OutputPath: filepath.Join(tmp, "out")}},
+		{name: "valid output in home", flags: flags.Flags{InputPath: cars, OutputPath: filepath.Join(home, "merged")}},
+		{name: "valid extra input", flags: flags.Flags{InputPath: cars, ExtraInputs: []string{donors}, OutputPath: filepath.Join(tmp, "out")}},
+		{name: "empty paths", flags: flags.Flags{}, want: []string{"input path is empty", "output path is empty"}},
+		{name: "missing input", flags: flags.Flags{InputPath: filepath.Join(tmp, "missing"), OutputPath: filepath.Join(tmp, "out")}, want: []string{"does not exist"}},
+		{name: "input is a file", flags: flags.Flags{InputPath: file, OutputPath: filepath.Join(tmp, "out")}, want: []string{"is not a folder"}},
+		{name: "missing extra input", flags: flags.Flags{InputPath: cars, ExtraInputs: []string{filepath.Join(tmp, "missing")}, OutputPath: filepath.Join(tmp, "out")}, want: []string{"does not exist"}},
+		{name: "output is the input", flags: flags.Flags{InputPath: cars, OutputPath: cars}, want: []string{"is the input path"}},
+		{name: "output inside the input", flags: flags.Flags{InputPath: cars, OutputPath: filepath.Join(cars, "merged")}, want: []string{"is inside the input path"}},
+		{name: "output contains the input", flags: flags.Flags{InputPath: cars, OutputPath: tmp}, want: []string{"contains your home folder", "contains the input path"}},
+		{name: "output is an extra input", flags: flags.Flags{InputPath: cars, ExtraInputs: []string{donors}, OutputPath: donors}, want: []string{"is the input path"}},
+		{name: "output is a file", flags: flags.Flags{InputPath: cars, OutputPath: file}, want: []string{"is not a folder"}},
+		{name: "output parent missing", flags: flags.Flags{InputPath: cars, OutputPath: filepath.Join(tmp, "missing", "merged")}, want: []string{"does not exist"}},
+		{name: "output is the root", flags: flags.Flags{InputPath: cars, OutputPath: string(filepath.Separator)}, want: []string{"root of the file system", "contains your home folder", "contains the input path"}},
+		{name: "output is home", flags: flags.Flags{InputPath: cars, OutputPath: home}, want: []string{"is your home folder"}},
+		{name: "output contains home", flags: flags.Flags{InputPath: cars, OutputPath: filepath.Join(tmp, "home")}, want: []string{"contains your home folder"}},
+		{name: "output is another home", flags: flags.Flags{InputPath: cars, OutputPath: filepath.Join(tmp, "home", "other")}, want: []string{"is a home folder"}},
+		{name: "relative output inside the input", flags: flags.Flags{InputPath: cars, OutputPath: filepath.Join(cars, "..", "cars", "merged")}, want: []string{"is inside the input path"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := CheckPaths(test.flags)
+			if len(test.want) == 0 {
+				if err != nil {
+					t.Fatalf("CheckPaths() = %v, want nil", err)
+				}
+				return
+			}
+
+			var pathsErr *PathsError
+			if !errors.As(err, &pathsErr) {
+				t.Fatalf("CheckPaths() = %v, want a *PathsError", err)
+			}
+			if !errors.Is(err, exitcode.ErrValidation) {
+				t.Error("PathsError does not unwrap to ErrValidation")
+			}
+			if len(pathsErr.Problems) != len(test.want) {
+				t.Fatalf("problems = %q, want %d problems", pathsErr.Problems, len(test.want))
+			}
+			for i, want := range test.want {
+				if !strings.Contains(pathsErr.Problems[i], want) {
+					t.Errorf("problem %d = %q, want it to contain %q", i, pathsErr.Problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		parent string
+		child  string
+		want   bool
+	}{
+		{parent: "/a", child: "/a/b", want: true},
+		{parent: "/a", child: "/a/b/c", want: true},
+		{parent: "/a", child: "/a", want: false},
+		{parent: "/a/b", child: "/a", want: false},
+		{parent: "/a", child: "/ab", want: false},
+		{parent: "/a", child: "/a/..b", want: true},
+	}
+	for _, test := range tests {
+		parent, child := filepath.FromSlash(test.parent), filepath.FromSlash(test.child)
+		if got := contains(parent, child); got != test.want {
+			t.Errorf("contains(%q, %q) = %v, want %v", parent, child, got, test.want)
+		}
+	}
+}
@@ -2,14 +2,38 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+	"github.com/charmbracelet/log"
 )
 
-func LoadConfig() (*flags.Flags, error) {
-	configPath := "config.json"
-	flags := &flags.Flags{}
+const DefaultProfile = "default"
+
+// Config holds one set of flags per named profile, e.g. one per car pack.
+type Config struct {
+	Version       int
+	ActiveProfile string
+	Profiles      map[string]*flags.Flags
+}
+
+func New(_flags *flags.Flags) *Config {
+	return &Config{
+		Version:       CurrentVersion,
+		ActiveProfile: DefaultProfile,
+		Profiles:      map[string]*flags.Flags{DefaultProfile: _flags},
+	}
+}
+
+// LoadConfig reads the config file found by Path. It returns nil when there is no config yet.
+// Older configs are migrated to CurrentVersion and saved, keeping the old file as a backup.
+// Unknown fields and invalid values are rejected instead of being dropped.
+func LoadConfig() (*Config, error) {
+	configPath := Path()
 
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		return nil, nil
@@ -20,15 +44,168 @@ func LoadConfig() (*flags.Flags, error) {
 		return nil, err
 	}
 
-	err = json.Unmarshal(data, flags)
-	return flags, err
+	version, err := detectVersion(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", configPath, err)
+	}
+	migrated, err := migrate(data, version)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", configPath, err)
+	}
+
+	config := &Config{}
+	if err := decodeStrict(migrated, config); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", configPath, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", configPath, err)
+	}
+
+	if version < CurrentVersion {
+		backupPath := fmt.Sprintf("%s.v%d.bak", configPath, version)
+		if err := os.WriteFile(backupPath, data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to back up config before migrating it: %w", err)
+		}
+		log.Info("Migrated config", "from", version, "to", CurrentVersion, "backup", backupPath)
+		if err := SaveConfig(config); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func (c *Config) validate() error {
+	if len(c.Profiles) == 0 {
+		return fmt.Errorf("no profiles")
+	}
+	for _, name := range c.ProfileNames() {
+		profile := c.Profiles[name]
+		if name == "" {
+			return fmt.Errorf("profile with an empty name")
+		}
+		if profile == nil {
+			return fmt.Errorf("profile %q is empty", name)
+		}
+		if err := validateProfile(profile); err != nil {
+			return fmt.Errorf("profile %q: %w", name, err)
+		}
+	}
+	if _, ok := c.Profiles[c.ActiveProfile]; !ok {
+		return fmt.Errorf("ActiveProfile %q is not one of the profiles %v", c.ActiveProfile, c.ProfileNames())
+	}
+	return nil
+}
+
+// validateProfile rejects values a merge can't run with.
+func validateProfile(profile *flags.Flags) error {
+	if profile.Concurrency < 0 {
+		return fmt.Errorf("Concurrency is %d, it must be 0 or more", profile.Concurrency)
+	}
+	lists := []struct {
+		name   string
+		values []string
+	}{
+		{"ExtraInputs", profile.ExtraInputs},
+		{"ExcludedCars", profile.ExcludedCars},
+		{"Include", profile.Include},
+		{"Exclude", profile.Exclude},
+	}
+	for _, list := range lists {
+		for i, value := range list.values {
+			if strings.TrimSpace(value) == "" {
+				return fmt.Errorf("%s[%d] is empty", list.name, i)
+			}
+		}
+	}
+	return nil
 }
 
-func SaveConfig(flags *flags.Flags) error {
-	data, err := json.MarshalIndent(flags, "", "  ")
+func SaveConfig(config *Config) error {
+	config.Version = CurrentVersion
+	data, err := json.MarshalIndent(config, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile("config.json", data, 0644)
+	configPath := Path()
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(configPath, data, 0644)
+}
+
+// Active returns the flags of the active profile.
+func (c *Config) Active() *flags.Flags {
+	return c.Profiles[c.ActiveProfile]
+}
+
+// Profile returns the flags of the named profile, or of the active one when name is empty.
+func (c *Config) Profile(name string) (*flags.Flags, error) {
+	if name == "" {
+		name = c.ActiveProfile
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q, expected one of %v", name, c.ProfileNames())
+	}
+	return profile, nil
+}
+
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AddProfile stores a new profile under name without making it active.
+func (c *Config) AddProfile(name string, _flags *flags.Flags) error {
+	if name == "" {
+		return fmt.Errorf("profile name is empty")
+	}
+	if _, ok := c.Profiles[name]; ok {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+	c.Profiles[name] = _flags
+	return nil
+}
+
+// DuplicateProfile copies the profile from into a new profile named to.
+func (c *Config) DuplicateProfile(from string, to string) error {
+	profile, err := c.Profile(from)
+	if err != nil {
+		return err
+	}
+	duplicate := *profile
+	duplicate.ExtraInputs = append([]string(nil), profile.ExtraInputs...)
+	duplicate.ExcludedCars = append([]string(nil), profile.ExcludedCars...)
+	duplicate.Include = append([]string(nil), profile.Include...)
+	duplicate.Exclude = append([]string(nil), profile.Exclude...)
+	return c.AddProfile(to, &duplicate)
+}
+
+// DeleteProfile removes a profile. The last profile can't be deleted, and deleting the
+// active profile activates the first remaining one.
+func (c *Config) DeleteProfile(name string) error {
+	if _, err := c.Profile(name); err != nil {
+		return err
+	}
+	if len(c.Profiles) == 1 {
+		return fmt.Errorf("can't delete %q, it is the only profile", name)
+	}
+	delete(c.Profiles, name)
+	if c.ActiveProfile == name {
+		c.ActiveProfile = c.ProfileNames()[0]
+	}
+	return nil
+}
+
+func (c *Config) SetActive(name string) error {
+	if _, err := c.Profile(name); err != nil {
+		return err
+	}
+	c.ActiveProfile = name
+	return nil
 }
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		police  flags.Flags
+		wantErr string
+	}{
+		{name: "valid", police: flags.Flags{InputPath: "cars", Concurrency: 4, Include: []string{"stream/**"}}},
+		{name: "negative concurrency", police: flags.Flags{Concurrency: -1}, wantErr: `profile "police": Concurrency is -1`},
+		{name: "empty extra input", police: flags.Flags{ExtraInputs: []string{"extra", ""}}, wantErr: `profile "police": ExtraInputs[1] is empty`},
+		{name: "blank excluded car", police: flags.Flags{ExcludedCars: []string{" "}}, wantErr: `profile "police": ExcludedCars[0] is empty`},
+		{name: "empty include", police: flags.Flags{Include: []string{""}}, wantErr: `profile "police": Include[0] is empty`},
+		{name: "empty exclude", police: flags.Flags{Exclude: []string{"*.txt", ""}}, wantErr: `profile "police": Exclude[1] is empty`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := New(&flags.Flags{InputPath: "cars"})
+			c.Profiles["police"] = &test.police
+
+			err := c.validate()
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("validate() = %v, want an error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
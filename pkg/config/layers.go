@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+)
+
+const (
+	FileName  = "config.json"
+	AppName   = "FiveMCarsMerger"
+	EnvPrefix = "FIVEMMERGER_"
+)
+
+// explicitPath is the config file given with --config.
+var explicitPath string
+
+// SetPath makes every later load and save use path instead of looking for config.json.
+func SetPath(path string) {
+	explicitPath = path
+}
+
+// Path returns the config file to use: the --config path, then $FIVEMMERGER_CONFIG, then an
+// existing config.json in the user config dir (XDG_CONFIG_HOME on Linux), then one in the
+// working directory, where older versions kept it, then one next to the binary. Without any
+// config, a new one is created in the user config dir.
+func Path() string {
+	if explicitPath != "" {
+		return explicitPath
+	}
+	if path := os.Getenv(EnvPrefix + "CONFIG"); path != "" {
+		return path
+	}
+
+	var candidates []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		candidates = append(candidates, filepath.Join(dir, AppName, FileName))
+	}
+	if path, err := filepath.Abs(FileName); err == nil {
+		candidates = append(candidates, path)
+	}
+	if dir, err := binaryDir(); err == nil {
+		candidates = append(candidates, filepath.Join(dir, FileName))
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	if len(candidates) == 0 {
+		return FileName
+	}
+	return candidates[0]
+}
+
+// ResolvePath makes a path from the config file absolute. Relative paths are relative to the
+// directory of the config file, so the binary can run from any working directory.
+func ResolvePath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	dir, err := filepath.Abs(filepath.Dir(Path()))
+	if err != nil {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// ResolvePaths resolves every path like ResolvePath.
+func ResolvePaths(paths []string) []string {
+	var resolved []string
+	for _, path := range paths {
+		resolved = append(resolved, ResolvePath(path))
+	}
+	return resolved
+}
+
+func binaryDir() (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+	return filepath.Dir(executable), nil
+}
+
+// Sources records where the effective value of every setting came from.
+type Sources map[string]string
+
+// Setting is one value of flags.Flags with the names it has in config.json, the environment
+// and on the command line.
+type Setting struct {
+	Name string
+	Env  string
+	Flag string
+	get  func(f *flags.Flags) string
+	set  func(f *flags.Flags, value string) error
+}
+
+func (s Setting) Value(f *flags.Flags) string {
+	return s.get(f)
+}
+
+var Settings = []Setting{
+	{
+		Name: "InputPath", Env: EnvPrefix + "INPUT_PATH", Flag: "input",
+		get: func(f *flags.Flags) string { return f.InputPath },
+		set: func(f *flags.Flags, value string) error { f.InputPath = value; return nil },
+	},
+	{
+		Name: "ExtraInputs", Env: EnvPrefix + "EXTRA_INPUTS", Flag: "extra-input",
+		get: func(f *flags.Flags) string { return strings.Join(f.ExtraInputs, ",") },
+		set: func(f *flags.Flags, value string) error { f.ExtraInputs = splitList(value); return nil },
+	},
+	{
+		Name: "OutputPath", Env: EnvPrefix + "OUTPUT_PATH", Flag: "output",
+		get: func(f *flags.Flags) string { return f.OutputPath },
+		set: func(f *flags.Flags, value string) error { f.OutputPath = value; return nil },
+	},
+	{
+		Name: "Clean", Env: EnvPrefix + "CLEAN", Flag: "clean",
+		get: func(f *flags.Flags) string { return strconv.FormatBool(f.Clean) },
+		set: func(f *flags.Flags, value string) (err error) { f.Clean, err = strconv.ParseBool(value); return err },
+	},
+	{
+		Name: "Verbose", Env: EnvPrefix + "VERBOSE", Flag: "verbose",
+		get: func(f *flags.Flags) string { return strconv.FormatBool(f.Verbose) },
+		set: func(f *flags.Flags, value string) (err error) { f.Verbose, err = strconv.ParseBool(value); return err },
+	},
+	{
+		Name: "Strict", Env: EnvPrefix + "STRICT", Flag: "strict",
+		get: func(f *flags.Flags) string { return strconv.FormatBool(f.Strict) },
+		set: func(f *flags.Flags, value string) (err error) { f.Strict, err = strconv.ParseBool(value); return err },
+	},
+	{
+		Name: "ExcludedCars", Env: EnvPrefix + "EXCLUDED_CARS",
+		get: func(f *flags.Flags) string { return strings.Join(f.ExcludedCars, ",") },
+		set: func(f *flags.Flags, value string) error { f.ExcludedCars = splitList(value); return nil },
+	},
+	{
+		Name: "Include", Env: EnvPrefix + "INCLUDE", Flag: "include",
+		get: func(f *flags.Flags) string { return strings.Join(f.Include, ",") },
+		set: func(f *flags.Flags, value string) error { f.Include = splitList(value); return nil },
+	},
+	{
+		Name: "Exclude", Env: EnvPrefix + "EXCLUDE", Flag: "exclude",
+		get: func(f *flags.Flags) string { return strings.Join(f.Exclude, ",") },
+		set: func(f *flags.Flags, value string) error { f.Exclude = splitList(value); return nil },
+	},
+	{
+		Name: "Overrides", Env: EnvPrefix + "OVERRIDES", Flag: "overrides",
+		get: func(f *flags.Flags) string { return f.Overrides },
+		set: func(f *flags.Flags, value string) error { f.Overrides = value; return nil },
+	},
+	{
+		Name: "Concurrency", Env: EnvPrefix + "CONCURRENCY", Flag: "concurrency",
+		get: func(f *flags.Flags) string { return strconv.Itoa(f.Concurrency) },
+		set: func(f *flags.Flags, value string) (err error) { f.Concurrency, err = strconv.Atoi(value); return err },
+	},
+}
+
+// SetAll records source for every setting.
+func (s Sources) SetAll(source string) {
+	for _, setting := range Settings {
+		s[setting.Name] = source
+	}
+}
+
+// ApplyEnv overrides settings with the FIVEMMERGER_* environment variables that are set.
+func ApplyEnv(f *flags.Flags, sources Sources) error {
+	for _, setting := range Settings {
+		value, ok := os.LookupEnv(setting.Env)
+		if !ok {
+			continue
+		}
+		if err := setting.set(f, value); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", setting.Env, value, err)
+		}
+		sources[setting.Name] = "env " + setting.Env
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string // config files that exist: "user" or "working"
+		want     string
+	}{
+		{name: "new config in the user config dir", want: "user"},
+		{name: "user config dir", existing: []string{"user"}, want: "user"},
+		{name: "legacy config in the working directory", existing: []string{"working"}, want: "working"},
+		{name: "user config dir before the working directory", existing: []string{"user", "working"}, want: "user"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tmp, err := filepath.EvalSymlinks(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("XDG_CONFIG_HOME", filepath.Join(tmp, "config"))
+			t.Setenv("HOME", tmp)
+			t.Setenv("AppData", filepath.Join(tmp, "config"))
+			t.Setenv(EnvPrefix+"CONFIG", "")
+			userDir, err := os.UserConfigDir()
+			if err != nil {
+				t.Fatal(err)
+			}
+			paths := map[string]string{
+				"user":    filepath.Join(userDir, AppName, FileName),
+				"working": filepath.Join(tmp, "work", FileName),
+			}
+			chdir(t, filepath.Join(tmp, "work"))
+			for _, existing := range test.existing {
+				if err := os.MkdirAll(filepath.Dir(paths[existing]), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(paths[existing], []byte("{}"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := Path(); got != paths[test.want] {
+				t.Errorf("Path() = %s, want %s", got, paths[test.want])
+			}
+		})
+	}
+}
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+}
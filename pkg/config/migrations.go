@@ -0,0 +1,86 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentVersion is the config version this build writes. Bump it together with a new
+// entry in migrations whenever the shape of config.json changes.
+const CurrentVersion = 2
+
+// A migration turns a config of one version into the next version. Migrations work on the
+// raw JSON, so they keep working when flags.Flags changes later on.
+type migration func(data []byte) ([]byte, error)
+
+// migrations upgrade a config from the version of their index to the next one.
+//
+//	0: flat flags.Flags, before profiles existed
+//	1: profiles without a version
+//	2: profiles with a version
+var migrations = []migration{
+	migrateToProfiles,
+	migrateToVersioned,
+}
+
+// detectVersion reads the Version field of a config. Configs from before versions existed
+// are told apart by their shape.
+func detectVersion(data []byte) (int, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return 0, err
+	}
+	if raw, ok := fields["Version"]; ok {
+		var version int
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return 0, fmt.Errorf("invalid Version %s", raw)
+		}
+		return version, nil
+	}
+	if _, ok := fields["Profiles"]; ok {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// migrate runs every migration from version up to CurrentVersion.
+func migrate(data []byte, version int) ([]byte, error) {
+	if version < 0 || version > CurrentVersion {
+		return nil, fmt.Errorf("unsupported config version %d, this build reads versions up to %d", version, CurrentVersion)
+	}
+	for ; version < CurrentVersion; version++ {
+		var err error
+		if data, err = migrations[version](data); err != nil {
+			return nil, fmt.Errorf("failed to migrate config from version %d: %w", version, err)
+		}
+	}
+	return data, nil
+}
+
+func migrateToProfiles(data []byte) ([]byte, error) {
+	legacyFlags := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &legacyFlags); err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]any{
+		"ActiveProfile": DefaultProfile,
+		"Profiles":      map[string]any{DefaultProfile: legacyFlags},
+	})
+}
+
+func migrateToVersioned(data []byte) ([]byte, error) {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	fields["Version"] = json.RawMessage("2")
+	return json.Marshal(fields)
+}
+
+// decodeStrict decodes data into value and fails on fields value doesn't have.
+func decodeStrict(data []byte, value any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(value)
+}
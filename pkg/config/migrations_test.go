@@ -0,0 +1,154 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		want    int
+		wantErr bool
+	}{
+		{name: "flat flags", json: `{"InputPath": "cars", "OutputPath": "merged"}`, want: 0},
+		{name: "profiles", json: `{"ActiveProfile": "default", "Profiles": {}}`, want: 1},
+		{name: "versioned", json: `{"Version": 2, "ActiveProfile": "default", "Profiles": {}}`, want: 2},
+		{name: "future version", json: `{"Version": 9}`, want: 9},
+		{name: "invalid version", json: `{"Version": "2"}`, wantErr: true},
+		{name: "not json", json: `InputPath=cars`, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := detectVersion([]byte(test.json))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("detectVersion() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if !test.wantErr && got != test.want {
+				t.Errorf("detectVersion() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		version int
+		wantErr string
+		check   func(t *testing.T, c *Config)
+	}{
+		{
+			name:    "flat flags become the default profile",
+			json:    `{"InputPath": "cars", "OutputPath": "merged", "Clean": true}`,
+			version: 0,
+			check: func(t *testing.T, c *Config) {
+				if c.ActiveProfile != DefaultProfile {
+					t.Errorf("ActiveProfile = %q, want %q", c.ActiveProfile, DefaultProfile)
+				}
+				profile := c.Profiles[DefaultProfile]
+				if profile == nil || profile.InputPath != "cars" || profile.OutputPath != "merged" || !profile.Clean {
+					t.Errorf("default profile = %+v", profile)
+				}
+			},
+		},
+		{
+			name:    "profiles get a version",
+			json:    `{"ActiveProfile": "police", "Profiles": {"police": {"InputPath": "police-cars"}}}`,
+			version: 1,
+			check: func(t *testing.T, c *Config) {
+				if c.ActiveProfile != "police" || c.Profiles["police"].InputPath != "police-cars" {
+					t.Errorf("config = %+v", c)
+				}
+			},
+		},
+		{
+			name:    "current version is unchanged",
+			json:    `{"Version": 2, "ActiveProfile": "default", "Profiles": {"default": {}}}`,
+			version: 2,
+		},
+		{name: "negative version", json: `{}`, version: -1, wantErr: "unsupported config version -1"},
+		{name: "newer version", json: `{}`, version: CurrentVersion + 1, wantErr: "unsupported config version"},
+		{name: "broken legacy config", json: `[1, 2]`, version: 0, wantErr: "failed to migrate config from version 0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			migrated, err := migrate([]byte(test.json), test.version)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("migrate() = %v, want an error containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("migrate() = %v", err)
+			}
+
+			c := &Config{}
+			if err := decodeStrict(migrated, c); err != nil {
+				t.Fatalf("migrated config %s does not decode: %v", migrated, err)
+			}
+			if c.Version != CurrentVersion {
+				t.Errorf("Version = %d, want %d", c.Version, CurrentVersion)
+			}
+			if test.check != nil {
+				test.check(t, c)
+			}
+		})
+	}
+}
+
+func TestLoadConfigMigratesWithBackup(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), FileName)
+	legacy := `{"InputPath": "cars", "OutputPath": "merged"}`
+	if err := os.WriteFile(configPath, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	SetPath(configPath)
+	t.Cleanup(func() { SetPath("") })
+
+	c, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() = %v", err)
+	}
+	if c.Version != CurrentVersion || c.Active().InputPath != "cars" {
+		t.Errorf("LoadConfig() = %+v", c)
+	}
+
+	backup, err := os.ReadFile(configPath + ".v0.bak")
+	if err != nil {
+		t.Fatalf("no backup of the old config: %v", err)
+	}
+	if string(backup) != legacy {
+		t.Errorf("backup = %s, want %s", backup, legacy)
+	}
+	if version, _ := detectVersion(mustRead(t, configPath)); version != CurrentVersion {
+		t.Errorf("saved config has version %d, want %d", version, CurrentVersion)
+	}
+}
+
+func TestLoadConfigRejectsUnknownFields(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(configPath, []byte(`{"Version": 2, "ActiveProfile": "default", "Profiles": {"default": {"InputPth": "cars"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	SetPath(configPath)
+	t.Cleanup(func() { SetPath("") })
+
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "InputPth") {
+		t.Errorf("LoadConfig() = %v, want an error naming InputPth", err)
+	}
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
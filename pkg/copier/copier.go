@@ -1,15 +1,20 @@
 package copier
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/dft"
 	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/plan"
 	fileutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/file"
+	poolutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/pool"
 	"github.com/charmbracelet/log"
 )
 
@@ -17,47 +22,52 @@ type Copier interface {
 	CopyStreamFilesToOutputDirectory(streamFiles []dft.StreamFile) error
 	CopyDataFilesToOutputDirectory(dataFiles []dft.DataFile) error
 	CopyAudioFilesToOutputDirectory(audioFiles []dft.AudioFile) error
+	PlanStreamFiles(streamFiles []dft.StreamFile) []plan.Operation
+	PlanDataFiles(dataFiles []dft.DataFile) ([]plan.Operation, []dft.SkippedFile)
+	PlanAudioFiles(audioFiles []dft.AudioFile) []plan.Operation
+	Apply(ctx context.Context, ops []plan.Operation, onCopied func(op plan.Operation)) error
+	SetLogger(logger *log.Logger)
 }
 
 type copier struct {
-	Flags flags.Flags
+	Flags  flags.Flags
+	Logger *log.Logger
 }
 
 func New(_flags flags.Flags) Copier {
-	return &copier{Flags: _flags}
+	return &copier{Flags: _flags, Logger: log.Default()}
+}
+
+// SetLogger makes the copier log to logger instead of the default logger.
+func (c *copier) SetLogger(logger *log.Logger) {
+	c.Logger = logger
 }
 
 func (c *copier) CopyDataFilesToOutputDirectory(dataFiles []dft.DataFile) error {
 	// First ensure the base output directory exists
-	log.Info("Creating base output directory", "path", c.Flags.OutputPath)
+	c.Logger.Info("Creating base output directory", "path", c.Flags.OutputPath)
 	if err := os.MkdirAll(c.Flags.OutputPath, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	// Create data directory with explicit path
-	baseDataPath := filepath.Join(c.Flags.OutputPath, "data")
-	log.Info("Creating data directory", "path", baseDataPath)
-	if err := os.MkdirAll(baseDataPath, 0755); err != nil {
-		return fmt.Errorf("failed to create data directory: %w", err)
-	}
+	ops, _ := c.PlanDataFiles(dataFiles)
+	return c.Apply(context.Background(), ops, nil)
+}
 
-	// Create all subdirectories with explicit paths and verification
-	dataDirs := []string{"vehicles", "carcols", "carvariations", "handling", "vehiclelayouts", "contentunlocks"}
-	for _, dir := range dataDirs {
-		fullPath := filepath.Join(baseDataPath, dir)
-		log.Debug("Creating subdirectory", "path", fullPath)
-		if err := os.MkdirAll(fullPath, 0755); err != nil {
-			return fmt.Errorf("failed to create %s directory: %w", dir, err)
-		}
-		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
-			return fmt.Errorf("directory creation failed for: %s", fullPath)
-		}
-	}
+func (c *copier) CopyStreamFilesToOutputDirectory(streamFiles []dft.StreamFile) error {
+	return c.Apply(context.Background(), c.PlanStreamFiles(streamFiles), nil)
+}
 
-	// Map to store vehicle names
+func (c *copier) CopyAudioFilesToOutputDirectory(audioFiles []dft.AudioFile) error {
+	return c.Apply(context.Background(), c.PlanAudioFiles(audioFiles), nil)
+}
+
+// VehicleNames maps every directory holding a vehicles.meta to the model name declared in it.
+// Data files are renamed after the vehicle of the directory they were found in.
+func VehicleNames(dataFiles []dft.DataFile) map[string]string {
 	vehicleNames := make(map[string]string)
+	re := regexp.MustCompile(`<modelName.*?>(.*)</modelName>`)
 
-	// First pass - get vehicle names from vehicles.meta
 	for _, dataFile := range dataFiles {
 		if dataFile.Type == dft.VEHICLES {
 			dirPath := filepath.Dir(dataFile.Path)
@@ -67,7 +77,6 @@ func (c *copier) CopyDataFilesToOutputDirectory(dataFiles []dft.DataFile) error
 				continue
 			}
 
-			re := regexp.MustCompile(`<modelName.*?>(.*)</modelName>`)
 			matches := re.FindStringSubmatch(string(content))
 			if len(matches) > 1 {
 				vehicleNames[dirPath] = strings.TrimSpace(matches[1])
@@ -76,77 +85,132 @@ func (c *copier) CopyDataFilesToOutputDirectory(dataFiles []dft.DataFile) error
 		}
 	}
 
-	// Second pass - copy files with correct names
+	return vehicleNames
+}
+
+func (c *copier) PlanDataFiles(dataFiles []dft.DataFile) ([]plan.Operation, []dft.SkippedFile) {
+	var ops []plan.Operation
+	var skipped []dft.SkippedFile
+	vehicleNames := VehicleNames(dataFiles)
+
 	for _, dataFile := range dataFiles {
 		dirPath := filepath.Dir(dataFile.Path)
 		vehicleName := vehicleNames[dirPath]
 		if vehicleName == "" {
 			log.Debug("No vehicle name found for directory", "dir", dirPath)
+			skipped = append(skipped, dft.SkippedFile{Path: dataFile.Path, Reason: "no vehicles.meta with a model name in its directory"})
 			continue
 		}
 
 		typeDir := strings.ToLower(dataFile.Type.String())
-		destPath := filepath.Join(baseDataPath, typeDir, fmt.Sprintf("%s_%s.meta", typeDir, vehicleName))
-
-		log.Debug("Copying file", "from", dataFile.Path, "to", destPath)
-		if _, err := fileutils.CopyFile(dataFile.Path, destPath); err != nil {
-			return fmt.Errorf("failed to copy file %s: %w", dataFile.Path, err)
-		}
+		ops = append(ops, newOperation(plan.DATA, dataFile.Path,
+			path.Join("data", typeDir, fmt.Sprintf("%s_%s.meta", typeDir, vehicleName)), dataFile.Type.String()))
 	}
 
-	return nil
+	return ops, skipped
 }
 
-func (c *copier) CopyStreamFilesToOutputDirectory(streamFiles []dft.StreamFile) error {
-	err := c.CreateDirectoryInOutput("stream")
-	if err != nil {
-		return err
+func (c *copier) PlanStreamFiles(streamFiles []dft.StreamFile) []plan.Operation {
+	var ops []plan.Operation
+	for _, streamFile := range streamFiles {
+		ops = append(ops, newOperation(plan.STREAM, streamFile.Path, path.Join("stream", streamFile.Name), ""))
 	}
+	return ops
+}
 
-	streamPath := c.Flags.OutputPath + "/stream/"
-
-	for _, streamFile := range streamFiles {
-		log.Debug("Copying file", "name", streamFile.Name)
-		_, err := fileutils.CopyFile(streamFile.Path, streamPath+streamFile.Name)
-		if err != nil {
-			return err
+func (c *copier) PlanAudioFiles(audioFiles []dft.AudioFile) []plan.Operation {
+	var ops []plan.Operation
+	for _, audio := range audioFiles {
+		if audio.IsConfig {
+			ops = append(ops, newOperation(plan.AUDIO, audio.Path, path.Join("audioconfig", audio.Name), ""))
+		} else {
+			ops = append(ops, newOperation(plan.AUDIO, audio.Path, path.Join("sfx", "dlc_"+audio.DLCFolder, audio.Name), ""))
 		}
 	}
-
-	return nil
+	return ops
 }
 
-func (c *copier) CopyAudioFilesToOutputDirectory(audioFiles []dft.AudioFile) error {
-	// Create audio directories
-	if err := c.CreateDirectoryInOutput("audioconfig"); err != nil {
-		return err
+func newOperation(kind plan.Kind, source string, destination string, _type string) plan.Operation {
+	action := plan.COPY
+	if filepath.Base(source) != path.Base(destination) {
+		action = plan.RENAME
 	}
-	if err := c.CreateDirectoryInOutput("sfx"); err != nil {
-		return err
+	return plan.Operation{
+		Action:      action,
+		Kind:        kind,
+		Source:      source,
+		Destination: destination,
+		Type:        _type,
 	}
+}
 
-	for _, audio := range audioFiles {
-		if audio.IsConfig {
-			destPath := filepath.Join(c.Flags.OutputPath, "audioconfig", audio.Name)
-			if _, err := fileutils.CopyFile(audio.Path, destPath); err != nil {
-				return err
-			}
-		} else {
-			// When creating the destination path:
-			dlcPath := filepath.Join(c.Flags.OutputPath, "sfx", "dlc_"+audio.DLCFolder)
+// Apply runs the operations on Flags.Concurrency workers, creating destination directories
+// as needed. Operations with the same destination run in order on one worker, so the last
+// one still wins. It stops between files once ctx is done. onCopied, when set, is called
+// after every file, one call at a time.
+func (c *copier) Apply(ctx context.Context, ops []plan.Operation, onCopied func(op plan.Operation)) error {
+	var groups [][]plan.Operation
+	groupOf := make(map[string]int)
+	for _, op := range ops {
+		i, ok := groupOf[op.Destination]
+		if !ok {
+			i = len(groups)
+			groupOf[op.Destination] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], op)
+	}
 
-			if err := os.MkdirAll(dlcPath, 0755); err != nil {
+	var mu sync.Mutex
+	return poolutils.Run(poolutils.Workers(c.Flags.Concurrency), len(groups), func(i int) error {
+		for _, op := range groups[i] {
+			if err := ctx.Err(); err != nil {
 				return err
 			}
-			destPath := filepath.Join(dlcPath, audio.Name)
-			if _, err := fileutils.CopyFile(audio.Path, destPath); err != nil {
+			if err := c.apply(op); err != nil {
 				return err
 			}
+			if onCopied != nil {
+				mu.Lock()
+				onCopied(op)
+				mu.Unlock()
+			}
+		}
+		return nil
+	})
+}
+
+func (c *copier) apply(op plan.Operation) error {
+	destPath := filepath.Join(c.Flags.OutputPath, filepath.FromSlash(op.Destination))
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", op.Destination, err)
+	}
+
+	if op.Rename != nil {
+		c.Logger.Debug("Copying file with renamed model", "from", op.Source, "to", destPath, "model", op.Rename.From, "renamed", op.Rename.To)
+		if err := copyRenamed(op.Source, destPath, op.Rename); err != nil {
+			return fmt.Errorf("failed to copy file %s: %w", op.Source, err)
 		}
+		return nil
+	}
+	c.Logger.Debug("Copying file", "from", op.Source, "to", destPath)
+	if _, err := fileutils.CopyFile(op.Source, destPath); err != nil {
+		return fmt.Errorf("failed to copy file %s: %w", op.Source, err)
 	}
 	return nil
 }
 
+// copyRenamed copies a data file and replaces every value that is exactly the old model name,
+// e.g. <modelName>adder</modelName> and <txdName>adder</txdName>.
+func copyRenamed(source string, destination string, rename *plan.Rename) error {
+	content, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	re := regexp.MustCompile(`(?i)>(\s*)` + regexp.QuoteMeta(rename.From) + `(\s*)<`)
+	return os.WriteFile(destination, re.ReplaceAll(content, []byte(">${1}"+rename.To+"${2}<")), 0644)
+}
+
 func (c *copier) CreateDirectoryInOutput(name string) error {
 	return os.MkdirAll(c.Flags.OutputPath+"/"+name, 0755)
 }
@@ -1,5 +1,7 @@
 package dft
 
+import "strings"
+
 type DataFileType int
 
 type AudioFile struct {
@@ -25,6 +27,16 @@ func (d DataFileType) String() string {
 	return [...]string{"CARCOLS", "CARVARIATIONS", "CONTENTUNLOCKS", "HANDLING", "VEHICLELAYOUTS", "VEHICLEMODELSETS", "VEHICLES", "WEAPONSFILE", "AUDIOFILE", "INVALID"}[d-1]
 }
 
+// ParseDataFileType returns the type named name, e.g. "CARCOLS", ignoring case.
+func ParseDataFileType(name string) (DataFileType, bool) {
+	for d := CARCOLS; d < INVALID; d++ {
+		if strings.EqualFold(d.String(), name) {
+			return d, true
+		}
+	}
+	return INVALID, false
+}
+
 func (d DataFileType) EnumIndex() int {
 	return int(d)
 }
@@ -39,3 +51,10 @@ type StreamFile struct {
 	Path string
 	Name string
 }
+
+// SkippedFile is a file of the input tree that is not copied to the output.
+type SkippedFile struct {
+	Path       string
+	Reason     string
+	UnknownTag string `json:",omitempty"` // root tag of metas the type identifier does not know
+}
@@ -0,0 +1,234 @@
+package differ
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/carfinder"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/dft"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+	fileutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/file"
+	xmlutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/xml"
+)
+
+// comparedTypes are the data files whose fields are compared.
+var comparedTypes = []dft.DataFileType{dft.VEHICLES, dft.HANDLING, dft.CARCOLS}
+
+type Changes struct {
+	OldPath string
+	NewPath string
+	Added   []string
+	Removed []string
+	Changed []CarChange
+}
+
+type CarChange struct {
+	Model         string
+	StreamAdded   []string
+	StreamRemoved []string
+	StreamChanged []string
+	DataAdded     []string
+	DataRemoved   []string
+	Fields        []FieldChange
+}
+
+type FieldChange struct {
+	Type string
+	Path string
+	Old  string
+	New  string
+}
+
+func (c *Changes) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Changed) == 0
+}
+
+func (c *CarChange) isEmpty() bool {
+	return len(c.StreamAdded) == 0 && len(c.StreamRemoved) == 0 && len(c.StreamChanged) == 0 &&
+		len(c.DataAdded) == 0 && len(c.DataRemoved) == 0 && len(c.Fields) == 0
+}
+
+type Differ interface {
+	Diff(oldPath string, newPath string) (*Changes, error)
+}
+
+type differ struct {
+	Flags flags.Flags
+}
+
+func New(_flags flags.Flags) Differ {
+	return &differ{Flags: _flags}
+}
+
+// Diff compares two merged resources car by car.
+func (d *differ) Diff(oldPath string, newPath string) (*Changes, error) {
+	oldCars, _, err := carfinder.ResourceCars(oldPath)
+	if err != nil {
+		return nil, err
+	}
+	newCars, _, err := carfinder.ResourceCars(newPath)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &Changes{OldPath: oldPath, NewPath: newPath}
+	oldByModel := byModel(oldCars)
+	newByModel := byModel(newCars)
+
+	for _, car := range newCars {
+		oldCar, ok := oldByModel[car.Model]
+		if !ok {
+			changes.Added = append(changes.Added, car.Model)
+			continue
+		}
+		change, err := d.compareCar(oldCar, car)
+		if err != nil {
+			return nil, err
+		}
+		if !change.isEmpty() {
+			changes.Changed = append(changes.Changed, *change)
+		}
+	}
+	for _, car := range oldCars {
+		if _, ok := newByModel[car.Model]; !ok {
+			changes.Removed = append(changes.Removed, car.Model)
+		}
+	}
+
+	return changes, nil
+}
+
+func (d *differ) compareCar(oldCar *carfinder.Car, newCar *carfinder.Car) (*CarChange, error) {
+	change := &CarChange{Model: newCar.Model}
+
+	oldStream := byName(oldCar.StreamFiles)
+	newStream := byName(newCar.StreamFiles)
+	for _, name := range sortedKeys(newStream) {
+		oldFile, ok := oldStream[name]
+		if !ok {
+			change.StreamAdded = append(change.StreamAdded, name)
+			continue
+		}
+		same, err := sameContent(oldFile, newStream[name])
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			change.StreamChanged = append(change.StreamChanged, name)
+		}
+	}
+	for _, name := range sortedKeys(oldStream) {
+		if _, ok := newStream[name]; !ok {
+			change.StreamRemoved = append(change.StreamRemoved, name)
+		}
+	}
+
+	oldData := byType(oldCar.DataFiles)
+	newData := byType(newCar.DataFiles)
+	for _, _type := range sortedKeys(newData) {
+		if _, ok := oldData[_type]; !ok {
+			change.DataAdded = append(change.DataAdded, _type)
+		}
+	}
+	for _, _type := range sortedKeys(oldData) {
+		if _, ok := newData[_type]; !ok {
+			change.DataRemoved = append(change.DataRemoved, _type)
+		}
+	}
+
+	for _, _type := range comparedTypes {
+		typeDir := strings.ToLower(_type.String())
+		oldFile, okOld := oldData[typeDir]
+		newFile, okNew := newData[typeDir]
+		if !okOld || !okNew {
+			continue
+		}
+		fields, err := compareFields(typeDir, oldFile, newFile)
+		if err != nil {
+			return nil, err
+		}
+		change.Fields = append(change.Fields, fields...)
+	}
+
+	return change, nil
+}
+
+func compareFields(typeDir string, oldFile string, newFile string) ([]FieldChange, error) {
+	oldFields, err := readFields(oldFile)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := readFields(newFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []FieldChange
+	for _, path := range sortedKeys(newFields) {
+		if oldValue := oldFields[path]; oldValue != newFields[path] {
+			changes = append(changes, FieldChange{Type: typeDir, Path: path, Old: oldValue, New: newFields[path]})
+		}
+	}
+	for _, path := range sortedKeys(oldFields) {
+		if _, ok := newFields[path]; !ok {
+			changes = append(changes, FieldChange{Type: typeDir, Path: path, Old: oldFields[path]})
+		}
+	}
+	return changes, nil
+}
+
+func readFields(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return xmlutils.Flatten(data)
+}
+
+func sameContent(a string, b string) (bool, error) {
+	hashA, err := fileutils.HashFile(a)
+	if err != nil {
+		return false, err
+	}
+	hashB, err := fileutils.HashFile(b)
+	if err != nil {
+		return false, err
+	}
+	return hashA == hashB, nil
+}
+
+func byModel(cars []*carfinder.Car) map[string]*carfinder.Car {
+	result := make(map[string]*carfinder.Car)
+	for _, car := range cars {
+		result[car.Model] = car
+	}
+	return result
+}
+
+func byName(paths []string) map[string]string {
+	result := make(map[string]string)
+	for _, path := range paths {
+		result[strings.ToLower(filepath.Base(path))] = path
+	}
+	return result
+}
+
+// byType maps the data folder of each data file to its path.
+func byType(paths []string) map[string]string {
+	result := make(map[string]string)
+	for _, path := range paths {
+		result[strings.ToLower(filepath.Base(filepath.Dir(path)))] = path
+	}
+	return result
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
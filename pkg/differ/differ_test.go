@@ -0,0 +1,209 @@
+package differ
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+)
+
+func vehiclesMeta(model string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<CVehicleModelInfo__InitDataList>
+  <InitDatas>
+    <Item>
+      <modelName>%s</modelName>
+      <handlingId>%sH</handlingId>
+    </Item>
+  </InitDatas>
+</CVehicleModelInfo__InitDataList>`, model, model)
+}
+
+func handlingMeta(name string, mass string) string {
+	return fmt.Sprintf(`<CHandlingDataMgr>
+  <HandlingData>
+    <Item type="CHandlingData">
+      <handlingName>%s</handlingName>
+      <fMass value="%s" />
+    </Item>
+  </HandlingData>
+</CHandlingDataMgr>`, name, mass)
+}
+
+// resource is the files of a merged resource by slash separated path.
+type resource map[string]string
+
+func baseResource() resource {
+	return resource{
+		"data/vehicles/vehicles_adder.meta": vehiclesMeta("adder"),
+		"data/handling/handling_adder.meta": handlingMeta("adderH", "1800.0"),
+		"stream/adder.yft":                  "adder model",
+		"stream/adder.ytd":                  "adder textures",
+	}
+}
+
+func (r resource) with(path string, content string) resource {
+	changed := resource{}
+	for p, c := range r {
+		changed[p] = c
+	}
+	if content == "" {
+		delete(changed, path)
+	} else {
+		changed[path] = content
+	}
+	return changed
+}
+
+func (r resource) write(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for path, content := range r {
+		full := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestDiff(t *testing.T) {
+	base := baseResource()
+	withT20 := base.
+		with("data/vehicles/vehicles_t20.meta", vehiclesMeta("t20")).
+		with("stream/t20.yft", "t20 model")
+
+	tests := []struct {
+		name        string
+		old         resource
+		new         resource
+		wantAdded   []string
+		wantRemoved []string
+		wantChanged []CarChange
+	}{
+		{name: "no changes", old: base, new: base},
+		{name: "car added", old: base, new: withT20, wantAdded: []string{"t20"}},
+		{name: "car removed", old: withT20, new: base, wantRemoved: []string{"t20"}},
+		{
+			name:        "stream file changed",
+			old:         base,
+			new:         base.with("stream/adder.ytd", "new adder textures"),
+			wantChanged: []CarChange{{Model: "adder", StreamChanged: []string{"adder.ytd"}}},
+		},
+		{
+			name: "stream files added and removed",
+			old:  base,
+			new:  base.with("stream/adder.ytd", "").with("stream/adder_hi.yft", "adder hi model"),
+			wantChanged: []CarChange{{
+				Model:         "adder",
+				StreamAdded:   []string{"adder_hi.yft"},
+				StreamRemoved: []string{"adder.ytd"},
+			}},
+		},
+		{
+			name:        "data file added",
+			old:         base,
+			new:         base.with("data/carvariations/carvariations_adder.meta", "<CVehicleModelInfoVariation />"),
+			wantChanged: []CarChange{{Model: "adder", DataAdded: []string{"carvariations"}}},
+		},
+		{
+			name: "handling field changed",
+			old:  base,
+			new:  base.with("data/handling/handling_adder.meta", handlingMeta("adderH", "2000.0")),
+			wantChanged: []CarChange{{
+				Model: "adder",
+				Fields: []FieldChange{{
+					Type: "handling",
+					Path: "CHandlingDataMgr/HandlingData/Item[adderH]/fMass@value",
+					Old:  "1800.0",
+					New:  "2000.0",
+				}},
+			}},
+		},
+		{
+			name:        "data file removed",
+			old:         base,
+			new:         base.with("data/handling/handling_adder.meta", ""),
+			wantChanged: []CarChange{{Model: "adder", DataRemoved: []string{"handling"}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			changes, err := New(flags.Flags{}).Diff(test.old.write(t), test.new.write(t))
+			if err != nil {
+				t.Fatalf("Diff() = %v", err)
+			}
+			if !reflect.DeepEqual(changes.Added, test.wantAdded) {
+				t.Errorf("Added = %v, want %v", changes.Added, test.wantAdded)
+			}
+			if !reflect.DeepEqual(changes.Removed, test.wantRemoved) {
+				t.Errorf("Removed = %v, want %v", changes.Removed, test.wantRemoved)
+			}
+			if !reflect.DeepEqual(changes.Changed, test.wantChanged) {
+				t.Errorf("Changed = %+v, want %+v", changes.Changed, test.wantChanged)
+			}
+			wantEmpty := len(test.wantAdded)+len(test.wantRemoved)+len(test.wantChanged) == 0
+			if changes.IsEmpty() != wantEmpty {
+				t.Errorf("IsEmpty() = %v, want %v", changes.IsEmpty(), wantEmpty)
+			}
+		})
+	}
+}
+
+func TestDiffNotAResource(t *testing.T) {
+	if _, err := New(flags.Flags{}).Diff(t.TempDir(), baseResource().write(t)); err == nil {
+		t.Error("Diff() of a folder without data = nil, want an error")
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes Changes
+		want    []string
+	}{
+		{name: "empty", changes: Changes{}, want: []string{"# Vehicle changelog", "No vehicle changes in this update."}},
+		{
+			name: "every section",
+			changes: Changes{
+				Added:   []string{"t20"},
+				Removed: []string{"zentorno"},
+				Changed: []CarChange{{
+					Model:         "adder",
+					StreamChanged: []string{"adder.ytd"},
+					DataAdded:     []string{"carcols"},
+					Fields:        []FieldChange{{Type: "handling", Path: "fMass@value", Old: "1800.0"}},
+				}},
+			},
+			want: []string{
+				"## New vehicles\n\n- `t20`",
+				"## Removed vehicles\n\n- `zentorno`",
+				"### `adder`",
+				"- Updated model and textures",
+				"- Added carcols data",
+				"- handling `fMass@value`: 1800.0 → (none)",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := test.changes.WriteMarkdown(&out); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("WriteMarkdown() = %q, want it to contain %q", out.String(), want)
+				}
+			}
+		})
+	}
+}
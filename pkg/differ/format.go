@@ -0,0 +1,97 @@
+package differ
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func (c *Changes) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(c)
+}
+
+func (c *Changes) WriteText(w io.Writer) error {
+	if c.IsEmpty() {
+		_, err := fmt.Fprintf(w, "No changes between %s and %s\n", c.OldPath, c.NewPath)
+		return err
+	}
+
+	for _, model := range c.Added {
+		fmt.Fprintf(w, "+ %s\n", model)
+	}
+	for _, model := range c.Removed {
+		fmt.Fprintf(w, "- %s\n", model)
+	}
+	for _, change := range c.Changed {
+		fmt.Fprintf(w, "~ %s\n", change.Model)
+		writeList(w, "    stream added:   ", change.StreamAdded)
+		writeList(w, "    stream removed: ", change.StreamRemoved)
+		writeList(w, "    stream changed: ", change.StreamChanged)
+		writeList(w, "    data added:     ", change.DataAdded)
+		writeList(w, "    data removed:   ", change.DataRemoved)
+		for _, field := range change.Fields {
+			fmt.Fprintf(w, "    %s %s: %s -> %s\n", field.Type, field.Path, orNone(field.Old), orNone(field.New))
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%d added, %d removed, %d changed\n", len(c.Added), len(c.Removed), len(c.Changed))
+	return err
+}
+
+// WriteMarkdown writes a changelog meant for players rather than developers.
+func (c *Changes) WriteMarkdown(w io.Writer) error {
+	fmt.Fprintln(w, "# Vehicle changelog")
+	if c.IsEmpty() {
+		_, err := fmt.Fprintln(w, "\nNo vehicle changes in this update.")
+		return err
+	}
+
+	if len(c.Added) > 0 {
+		fmt.Fprintln(w, "\n## New vehicles")
+		fmt.Fprintln(w)
+		for _, model := range c.Added {
+			fmt.Fprintf(w, "- `%s`\n", model)
+		}
+	}
+	if len(c.Removed) > 0 {
+		fmt.Fprintln(w, "\n## Removed vehicles")
+		fmt.Fprintln(w)
+		for _, model := range c.Removed {
+			fmt.Fprintf(w, "- `%s`\n", model)
+		}
+	}
+	if len(c.Changed) > 0 {
+		fmt.Fprintln(w, "\n## Updated vehicles")
+		for _, change := range c.Changed {
+			fmt.Fprintf(w, "\n### `%s`\n\n", change.Model)
+			if len(change.StreamAdded)+len(change.StreamRemoved)+len(change.StreamChanged) > 0 {
+				fmt.Fprintln(w, "- Updated model and textures")
+			}
+			if len(change.DataAdded) > 0 {
+				fmt.Fprintf(w, "- Added %s data\n", strings.Join(change.DataAdded, ", "))
+			}
+			if len(change.DataRemoved) > 0 {
+				fmt.Fprintf(w, "- Removed %s data\n", strings.Join(change.DataRemoved, ", "))
+			}
+			for _, field := range change.Fields {
+				fmt.Fprintf(w, "- %s `%s`: %s → %s\n", field.Type, field.Path, orNone(field.Old), orNone(field.New))
+			}
+		}
+	}
+	return nil
+}
+
+func writeList(w io.Writer, label string, items []string) {
+	if len(items) > 0 {
+		fmt.Fprintf(w, "%s%s\n", label, strings.Join(items, ", "))
+	}
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
@@ -0,0 +1,65 @@
+package exitcode
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+const (
+	Success    = 0
+	Failure    = 1 // unknown command or any error that fits no other code
+	Warnings   = 2 // finished, but with warnings
+	Validation = 3 // the input, config or resource did not pass validation
+	IO         = 4 // reading or writing files failed
+	Usage      = 64
+)
+
+// ErrValidation marks errors caused by the content being merged or checked rather than by the file system.
+var ErrValidation = errors.New("validation failed")
+
+// ErrUsage marks errors caused by how the command was called.
+var ErrUsage = errors.New("invalid usage")
+
+// WarningsError is returned by commands that succeeded with warnings.
+type WarningsError struct {
+	Count int
+}
+
+func (e *WarningsError) Error() string {
+	return fmt.Sprintf("finished with %d warnings", e.Count)
+}
+
+// Validationf returns an error wrapping ErrValidation.
+func Validationf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrValidation, fmt.Sprintf(format, args...))
+}
+
+// Usagef returns an error wrapping ErrUsage.
+func Usagef(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrUsage, fmt.Sprintf(format, args...))
+}
+
+// FromError maps the error returned by a command to its exit code. Only errors of the
+// file system count as I/O failures, everything unclassified is a Failure.
+func FromError(err error) int {
+	var warnings *WarningsError
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	var syscallErr *os.SyscallError
+	switch {
+	case err == nil:
+		return Success
+	case errors.As(err, &warnings):
+		return Warnings
+	case errors.Is(err, ErrValidation):
+		return Validation
+	case errors.Is(err, ErrUsage):
+		return Usage
+	case errors.As(err, &pathErr), errors.As(err, &linkErr), errors.As(err, &syscallErr):
+		return IO
+	default:
+		return Failure
+	}
+}
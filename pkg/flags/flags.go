@@ -1,8 +1,28 @@
 package flags
 
+import sliceutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/slice"
+
 type Flags struct {
-	Verbose    bool
-	InputPath  string
-	OutputPath string
-	Clean      bool
+	Verbose      bool
+	InputPath    string
+	ExtraInputs  []string // more input paths, each with a lower priority than InputPath and the ones before it
+	OutputPath   string
+	Clean        bool
+	Strict       bool
+	ExcludedCars []string // top level folders of the input path that are left out of merges
+	Include      []string // gitignore-style patterns, when set only matching files are merged
+	Exclude      []string // gitignore-style patterns of files and folders that are never merged
+	Overrides    string   // overrides file, overrides.json next to the config when empty
+	Concurrency  int      // files walked, identified and copied at once, the number of CPUs when 0
+	Rebuild      bool     `json:"-"` // ignore the build cache of the output and copy every file again
+}
+
+// InputRoots returns every input path, highest priority first.
+func (f Flags) InputRoots() []string {
+	var roots []string
+	if f.InputPath != "" {
+		roots = append(roots, f.InputPath)
+	}
+	roots = append(roots, f.ExtraInputs...)
+	return sliceutils.RemoveDuplicates(roots)
 }
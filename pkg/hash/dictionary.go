@@ -0,0 +1,107 @@
+package hash
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DictionaryFile is the name of the reverse dictionary, which is kept next to config.json.
+const DictionaryFile = "hashes.json"
+
+// DictionaryPath is where Update reads and writes the dictionary. Callers point it next to
+// the config file they use.
+var DictionaryPath = DictionaryFile
+
+// Kinds of names the dictionary knows.
+const (
+	MODEL    = "model"
+	HANDLING = "handling"
+	AUDIO    = "audio"
+	RPF      = "rpf"
+)
+
+// Entry is a name that hashes to a key of the dictionary.
+type Entry struct {
+	Name string
+	Kind string
+	Car  string `json:",omitempty"` // model of the car the name belongs to, empty for archive names
+}
+
+// Dictionary maps formatted hashes back to the names the merger and extractor have seen.
+type Dictionary struct {
+	Entries map[string][]Entry
+}
+
+// LoadDictionary reads the dictionary at path. A missing file is an empty dictionary.
+func LoadDictionary(path string) (*Dictionary, error) {
+	dictionary := &Dictionary{Entries: make(map[string][]Entry)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return dictionary, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &dictionary.Entries); err != nil {
+		return nil, err
+	}
+	if dictionary.Entries == nil {
+		dictionary.Entries = make(map[string][]Entry)
+	}
+	return dictionary, nil
+}
+
+// Update loads the dictionary at DictionaryPath, lets fn add to it and saves it again.
+func Update(fn func(dictionary *Dictionary)) error {
+	dictionary, err := LoadDictionary(DictionaryPath)
+	if err != nil {
+		return err
+	}
+	fn(dictionary)
+	return dictionary.Save(DictionaryPath)
+}
+
+// Add records name of the car with model car under its hash. Empty names and names already
+// known for the kind and car are ignored.
+func (d *Dictionary) Add(kind string, name string, car string) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	car = strings.ToLower(strings.TrimSpace(car))
+	if name == "" {
+		return
+	}
+	key := Format(Joaat(name))
+	for _, entry := range d.Entries[key] {
+		if entry.Name == name && entry.Kind == kind && entry.Car == car {
+			return
+		}
+	}
+	d.Entries[key] = append(d.Entries[key], Entry{Name: name, Kind: kind, Car: car})
+	sort.Slice(d.Entries[key], func(i, j int) bool {
+		a, b := d.Entries[key][i], d.Entries[key][j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Car < b.Car
+	})
+}
+
+func (d *Dictionary) Lookup(h uint32) []Entry {
+	return d.Entries[Format(h)]
+}
+
+func (d *Dictionary) Save(path string) error {
+	data, err := json.MarshalIndent(d.Entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
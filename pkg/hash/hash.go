@@ -0,0 +1,52 @@
+package hash
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Joaat is the Jenkins one-at-a-time hash GTA uses for model, handling and audio names.
+// Names are hashed lowercase, like the game does.
+func Joaat(name string) uint32 {
+	var h uint32
+	for _, c := range []byte(strings.ToLower(name)) {
+		h += uint32(c)
+		h += h << 10
+		h ^= h >> 6
+	}
+	h += h << 3
+	h ^= h >> 11
+	h += h << 15
+	return h
+}
+
+// Format writes a hash the way the dictionary and the logs of the tool show it.
+func Format(h uint32) string {
+	return fmt.Sprintf("0x%08X", h)
+}
+
+// Parse reads a hash as hex with a 0x prefix, as unsigned decimal or as the signed decimal
+// that server logs print.
+func Parse(value string) (uint32, error) {
+	value = strings.TrimSpace(value)
+	if hex, ok := strings.CutPrefix(strings.ToLower(value), "0x"); ok {
+		h, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid hash %q: %w", value, err)
+		}
+		return uint32(h), nil
+	}
+	if strings.HasPrefix(value, "-") {
+		h, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid hash %q: %w", value, err)
+		}
+		return uint32(int32(h)), nil
+	}
+	h, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hash %q: %w", value, err)
+	}
+	return uint32(h), nil
+}
@@ -0,0 +1,108 @@
+package hash
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestJoaat(t *testing.T) {
+	// Model hashes of vanilla vehicles
+	tests := []struct {
+		name string
+		want uint32
+	}{
+		{name: "", want: 0},
+		{name: "adder", want: 0xB779A091},
+		{name: "zentorno", want: 0xAC5DF515},
+		{name: "t20", want: 0x6322B39A},
+		{name: "police", want: 0x79FBB0C5},
+		{name: "infernus", want: 0x18F25AC7},
+		{name: "sultan", want: 0x39DA2754},
+		{name: "ADDER", want: 0xB779A091},
+		{name: "Zentorno", want: 0xAC5DF515},
+	}
+	for _, test := range tests {
+		if got := Joaat(test.name); got != test.want {
+			t.Errorf("Joaat(%q) = %s, want %s", test.name, Format(got), Format(test.want))
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    uint32
+		wantErr bool
+	}{
+		{value: "0xB779A091", want: 0xB779A091},
+		{value: "0xb779a091", want: 0xB779A091},
+		{value: " 0XB779A091 ", want: 0xB779A091},
+		{value: "3078201489", want: 0xB779A091},
+		{value: "-1216765807", want: 0xB779A091},
+		{value: "0", want: 0},
+		{value: "0x1FFFFFFFF", wantErr: true},
+		{value: "4294967296", wantErr: true},
+		{value: "-2147483649", wantErr: true},
+		{value: "adder", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", test.value, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && got != test.want {
+			t.Errorf("Parse(%q) = %s, want %s", test.value, Format(got), Format(test.want))
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if got := Format(0xB779A091); got != "0xB779A091" {
+		t.Errorf("Format() = %s, want 0xB779A091", got)
+	}
+	if got := Format(0x1A); got != "0x0000001A" {
+		t.Errorf("Format() = %s, want 0x0000001A", got)
+	}
+}
+
+func TestDictionary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", DictionaryFile)
+	dictionary, err := LoadDictionary(path)
+	if err != nil {
+		t.Fatalf("LoadDictionary() of a missing file = %v", err)
+	}
+	dictionary.Add(MODEL, "Adder", "adder")
+	dictionary.Add(MODEL, "adder ", "Adder")
+	dictionary.Add(AUDIO, "adder", "adder")
+	dictionary.Add(AUDIO, "adder", "adder2")
+	dictionary.Add(RPF, "adder", "")
+	dictionary.Add(HANDLING, "", "adder")
+	if err := dictionary.Save(path); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+
+	loaded, err := LoadDictionary(path)
+	if err != nil {
+		t.Fatalf("LoadDictionary() = %v", err)
+	}
+	entries := loaded.Lookup(0xB779A091)
+	want := []Entry{
+		{Name: "adder", Kind: AUDIO, Car: "adder"},
+		{Name: "adder", Kind: AUDIO, Car: "adder2"},
+		{Name: "adder", Kind: MODEL, Car: "adder"},
+		{Name: "adder", Kind: RPF},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Lookup() = %v, want %v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("Lookup()[%d] = %v, want %v", i, entries[i], want[i])
+		}
+	}
+	if len(loaded.Entries) != 1 {
+		t.Errorf("dictionary has %d hashes, want 1", len(loaded.Entries))
+	}
+}
@@ -0,0 +1,171 @@
+package ignore
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// FileName is the per-folder ignore file, read like a .gitignore.
+const FileName = ".mergerignore"
+
+// Rule is one gitignore-style pattern.
+type Rule struct {
+	Pattern string
+	Source  string // where the pattern came from, e.g. a .mergerignore path or "config"
+	base    string // folder the pattern is relative to, slash separated, empty for the root
+	negate  bool
+	dirOnly bool
+	regex   *regexp.Regexp
+}
+
+// Matcher decides which paths of an input tree are ignored. Later rules override earlier
+// ones, so a .mergerignore deeper in the tree can re-include what a parent excluded.
+type Matcher struct {
+	rules []Rule
+}
+
+// Add parses patterns relative to the folder base. Blank lines and lines starting with # are skipped.
+func (m *Matcher) Add(base string, source string, patterns []string) {
+	for _, pattern := range patterns {
+		if rule, ok := parseRule(base, source, pattern); ok {
+			m.rules = append(m.rules, rule)
+		}
+	}
+}
+
+// AddFile adds the patterns of an ignore file in the folder base. A missing file adds nothing.
+func (m *Matcher) AddFile(base string, filePath string) error {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	m.Add(base, filePath, patterns)
+	return nil
+}
+
+// Match reports whether the slash separated path rel is ignored and the rule that decided it.
+func (m *Matcher) Match(rel string, isDir bool) (bool, *Rule) {
+	var matched *Rule
+	for i := range m.rules {
+		rule := &m.rules[i]
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		relToBase := rel
+		if rule.base != "" {
+			var ok bool
+			if relToBase, ok = strings.CutPrefix(rel, rule.base+"/"); !ok {
+				continue
+			}
+		}
+		if rule.regex.MatchString(relToBase) {
+			matched = rule
+		}
+	}
+	return matched != nil && !matched.negate, matched
+}
+
+// Clone returns a copy of the matcher that files can be added to without changing m.
+func (m *Matcher) Clone() *Matcher {
+	return &Matcher{rules: append([]Rule(nil), m.rules...)}
+}
+
+// Empty reports whether the matcher has no rules.
+func (m *Matcher) Empty() bool {
+	return len(m.rules) == 0
+}
+
+func parseRule(base string, source string, pattern string) (Rule, bool) {
+	line := strings.TrimRight(pattern, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return Rule{}, false
+	}
+
+	rule := Rule{Pattern: line, Source: source, base: strings.Trim(base, "/")}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	// Patterns with a slash are anchored to their folder, others match at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return Rule{}, false
+	}
+
+	expression := GlobToRegex(line)
+	if !anchored {
+		expression = "(.*/)?" + expression
+	}
+	// Case insensitive like the file systems most packs are made on
+	regex, err := regexp.Compile("(?i)^" + expression + "$")
+	if err != nil {
+		return Rule{}, false
+	}
+	rule.regex = regex
+	return rule, true
+}
+
+// GlobToRegex translates a slash separated glob into a regular expression without anchors.
+// * and ? stay within a folder, ** also matches across folders.
+func GlobToRegex(glob string) string {
+	var expression strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			expression.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			expression.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			expression.WriteString(".*")
+			i++
+		case c == '*':
+			expression.WriteString("[^/]*")
+		case c == '?':
+			expression.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				expression.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expression.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			expression.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			expression.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return expression.String()
+}
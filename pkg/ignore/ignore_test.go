@@ -0,0 +1,117 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		base     string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{name: "no rules", path: "adder/stream/adder.yft", want: false},
+		{name: "extension at any depth", patterns: []string{"*.bak"}, path: "adder/stream/adder.yft.bak", want: true},
+		{name: "extension does not match others", patterns: []string{"*.bak"}, path: "adder/stream/adder.yft", want: false},
+		{name: "case insensitive", patterns: []string{"*.BAK"}, path: "adder/Notes.bak", want: true},
+		{name: "folder name at any depth", patterns: []string{"backup"}, path: "adder/backup", isDir: true, want: true},
+		{name: "dir only pattern skips files", patterns: []string{"backup/"}, path: "adder/backup", want: false},
+		{name: "dir only pattern matches folders", patterns: []string{"backup/"}, path: "adder/backup", isDir: true, want: true},
+		{name: "anchored pattern", patterns: []string{"/adder"}, path: "adder", isDir: true, want: true},
+		{name: "anchored pattern not deeper", patterns: []string{"/adder"}, path: "cars/adder", isDir: true, want: false},
+		{name: "pattern with slash is anchored", patterns: []string{"adder/stream"}, path: "cars/adder/stream", isDir: true, want: false},
+		{name: "star stays in one folder", patterns: []string{"adder/*.yft"}, path: "adder/stream/adder.yft", want: false},
+		{name: "double star crosses folders", patterns: []string{"adder/**/*.yft"}, path: "adder/stream/hi/adder.yft", want: true},
+		{name: "leading double star", patterns: []string{"**/old"}, path: "a/b/old", isDir: true, want: true},
+		{name: "trailing double star", patterns: []string{"adder/**"}, path: "adder/stream/adder.yft", want: true},
+		{name: "question mark", patterns: []string{"car?.meta"}, path: "car1.meta", want: true},
+		{name: "character class", patterns: []string{"car[0-9].meta"}, path: "carx.meta", want: false},
+		{name: "negated character class", patterns: []string{"car[!0-9].meta"}, path: "carx.meta", want: true},
+		{name: "negation re-includes", patterns: []string{"*.meta", "!vehicles.meta"}, path: "adder/vehicles.meta", want: false},
+		{name: "later rule wins", patterns: []string{"!vehicles.meta", "*.meta"}, path: "adder/vehicles.meta", want: true},
+		{name: "escaped hash", patterns: []string{`\#notes`}, path: "#notes", want: true},
+		{name: "comments and blank lines", patterns: []string{"# *.yft", "", "   "}, path: "adder.yft", want: false},
+		{name: "relative to base", patterns: []string{"*.txt"}, base: "adder", path: "adder/readme.txt", want: true},
+		{name: "outside of base", patterns: []string{"*.txt"}, base: "adder", path: "zentorno/readme.txt", want: false},
+		{name: "anchored to base", patterns: []string{"/stream"}, base: "adder", path: "adder/stream", isDir: true, want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &Matcher{}
+			m.Add(test.base, "test", test.patterns)
+			if got, _ := m.Match(test.path, test.isDir); got != test.want {
+				t.Errorf("Match(%q) = %v, want %v", test.path, got, test.want)
+			}
+		})
+	}
+}
+
+func TestMatchReturnsDecidingRule(t *testing.T) {
+	m := &Matcher{}
+	m.Add("", "config", []string{"*.meta"})
+	m.Add("adder", "adder/.mergerignore", []string{"!handling.meta"})
+
+	ignored, rule := m.Match("adder/handling.meta", false)
+	if ignored || rule == nil || rule.Source != "adder/.mergerignore" || rule.Pattern != "!handling.meta" {
+		t.Errorf("Match() = %v, %+v, want the negated rule from adder/.mergerignore", ignored, rule)
+	}
+	ignored, rule = m.Match("zentorno/handling.meta", false)
+	if !ignored || rule == nil || rule.Source != "config" {
+		t.Errorf("Match() = %v, %+v, want the rule from config", ignored, rule)
+	}
+}
+
+func TestAddFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, FileName)
+	if err := os.WriteFile(path, []byte("# backups\r\n*.bak\r\n\r\n!keep.bak\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Matcher{}
+	if err := m.AddFile("adder", path); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddFile("adder", filepath.Join(dir, "missing")); err != nil {
+		t.Errorf("AddFile() of a missing file = %v, want nil", err)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "adder/old.bak", want: true},
+		{path: "adder/keep.bak", want: false},
+		{path: "zentorno/old.bak", want: false},
+	}
+	for _, test := range tests {
+		if got, _ := m.Match(test.path, false); got != test.want {
+			t.Errorf("Match(%q) = %v, want %v", test.path, got, test.want)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	m := &Matcher{}
+	m.Add("", "config", []string{"*.bak"})
+	clone := m.Clone()
+	clone.Add("", "adder", []string{"*.txt"})
+
+	if ignored, _ := m.Match("readme.txt", false); ignored {
+		t.Error("adding to the clone changed the original")
+	}
+	if ignored, _ := clone.Match("readme.txt", false); !ignored {
+		t.Error("clone lost its own rule")
+	}
+	if ignored, _ := clone.Match("old.bak", false); !ignored {
+		t.Error("clone lost the rules of the original")
+	}
+	if !(&Matcher{}).Empty() || m.Empty() {
+		t.Error("Empty() is wrong")
+	}
+}
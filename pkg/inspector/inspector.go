@@ -0,0 +1,120 @@
+package inspector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/carfinder"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/overrides"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/plan"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/scanner"
+)
+
+type Inventory struct {
+	InputPath   string
+	ExtraInputs []string `json:",omitempty"`
+	Cars        []*carfinder.Car
+	Unassigned  []string
+	Shadowed    []plan.Shadowed `json:",omitempty"`
+}
+
+type Inspector interface {
+	Inspect() (*Inventory, error)
+}
+
+type inspector struct {
+	Flags   flags.Flags
+	Scanner scanner.Scanner
+}
+
+func New(_flags flags.Flags) Inspector {
+	return &inspector{
+		Flags:   _flags,
+		Scanner: scanner.New(_flags),
+	}
+}
+
+// Inspect scans the input paths like a merge does and groups their files by car without
+// copying anything. Copies shadowed by an input path with a higher priority are left out.
+func (i *inspector) Inspect() (*Inventory, error) {
+	overridesPath := i.Flags.Overrides
+	if overridesPath == "" {
+		overridesPath = overrides.DefaultPath
+	}
+	carOverrides, err := overrides.Load(overridesPath)
+	if err != nil {
+		return nil, err
+	}
+	roots, _, shadowed, err := ScanRoots(i.Scanner, i.Flags.InputRoots(), carOverrides)
+	if err != nil {
+		return nil, err
+	}
+
+	inventory := &Inventory{InputPath: i.Flags.InputPath, Shadowed: shadowed}
+	if paths := i.Flags.InputRoots(); len(paths) > 1 {
+		inventory.ExtraInputs = paths[1:]
+	}
+	for _, root := range roots {
+		cars, unassigned := carfinder.GroupCars(root.Path, root.Result.StreamFiles, root.Result.DataFiles, root.Result.AudioFiles)
+		inventory.Cars = append(inventory.Cars, cars...)
+		inventory.Unassigned = append(inventory.Unassigned, unassigned...)
+	}
+	return inventory, nil
+}
+
+func (inv *Inventory) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(inv)
+}
+
+func (inv *Inventory) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODEL\tFOLDER\tSTREAM FILES\tDATA TYPES\tAUDIO PACKS\tMISSING")
+	for _, car := range inv.Cars {
+		var streamNames []string
+		for _, streamFile := range car.StreamFiles {
+			streamNames = append(streamNames, filepath.Base(streamFile))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			car.Model,
+			car.Folder,
+			orDash(strings.Join(streamNames, ", ")),
+			orDash(strings.ToLower(strings.Join(car.DataTypes, ", "))),
+			orDash(strings.Join(car.AudioPacks, ", ")),
+			orDash(strings.Join(car.Missing, ", ")))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\n%d cars found in %s\n", len(inv.Cars), strings.Join(append([]string{inv.InputPath}, inv.ExtraInputs...), ", "))
+	if len(inv.Shadowed) > 0 {
+		fmt.Fprintf(w, "\n%d copies left out for an input path with a higher priority:\n", len(inv.Shadowed))
+		for _, loser := range inv.Shadowed {
+			fmt.Fprintf(w, "  %s %s from %s, kept from %s\n", loser.Kind, loser.Name, loser.Loser, loser.Winner)
+		}
+	}
+	if len(inv.Unassigned) > 0 {
+		fmt.Fprintf(w, "\n%d files could not be matched to a car:\n", len(inv.Unassigned))
+		for _, path := range inv.Unassigned {
+			if rel, err := filepath.Rel(inv.InputPath, path); err == nil && !strings.HasPrefix(rel, "..") {
+				path = rel
+			}
+			fmt.Fprintf(w, "  %s\n", path)
+		}
+	}
+	return nil
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,139 @@
+package inspector
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/carfinder"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/dft"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/overrides"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/plan"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/scanner"
+)
+
+// Root is what one input path adds to a merge.
+type Root struct {
+	Path   string
+	Result *scanner.Result
+}
+
+// ScanRoots scans every input path, highest priority first, and applies the overrides to
+// each. A car whose model or a stream file whose name is already in a higher priority
+// input path is left out and recorded as shadowed.
+func ScanRoots(s scanner.Scanner, paths []string, carOverrides overrides.Overrides) ([]Root, *overrides.Changes, []plan.Shadowed, error) {
+	var roots []Root
+	changes := &overrides.Changes{}
+	var shadowed []plan.Shadowed
+
+	models := make(map[string]string)      // model -> car folder that won it
+	streamNames := make(map[string]string) // lowercase stream file name -> file that won it
+
+	for _, root := range paths {
+		result, err := s.ScanRoot(root)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		changes.Merge(carOverrides.Apply(root, result))
+
+		lost := make(map[string]string)
+		kept := make(map[string]bool)
+		cars, _ := carfinder.GroupCars(root, result.StreamFiles, result.DataFiles, result.AudioFiles)
+		for _, car := range cars {
+			winner, ok := models[car.Model]
+			if !ok {
+				for _, file := range carFiles(car) {
+					kept[file] = true
+				}
+				continue
+			}
+			shadowed = append(shadowed, plan.Shadowed{Kind: "model", Name: car.Model, Winner: winner, Loser: filepath.Join(root, car.Folder)})
+			for _, file := range carFiles(car) {
+				lost[file] = winner
+			}
+		}
+		// Files the shadowed cars share with other cars, like a vehicles.meta of several cars, stay
+		for file := range kept {
+			delete(lost, file)
+		}
+		for _, car := range cars {
+			if _, ok := models[car.Model]; !ok {
+				models[car.Model] = filepath.Join(root, car.Folder)
+			}
+		}
+
+		for _, streamFile := range result.StreamFiles {
+			if _, ok := lost[streamFile.Path]; ok {
+				continue
+			}
+			if winner, ok := streamNames[strings.ToLower(streamFile.Name)]; ok {
+				shadowed = append(shadowed, plan.Shadowed{Kind: "stream", Name: streamFile.Name, Winner: winner, Loser: streamFile.Path})
+				lost[streamFile.Path] = winner
+			}
+		}
+		for _, streamFile := range result.StreamFiles {
+			name := strings.ToLower(streamFile.Name)
+			if _, ok := streamNames[name]; !ok && lost[streamFile.Path] == "" {
+				streamNames[name] = streamFile.Path
+			}
+		}
+
+		removeShadowed(result, lost)
+		roots = append(roots, Root{Path: root, Result: result})
+	}
+
+	return roots, changes, shadowed, nil
+}
+
+// Merge returns the files of every root in one result, highest priority first.
+func Merge(roots []Root) *scanner.Result {
+	merged := &scanner.Result{}
+	for _, root := range roots {
+		merged.StreamFiles = append(merged.StreamFiles, root.Result.StreamFiles...)
+		merged.DataFiles = append(merged.DataFiles, root.Result.DataFiles...)
+		merged.AudioFiles = append(merged.AudioFiles, root.Result.AudioFiles...)
+		merged.Skipped = append(merged.Skipped, root.Result.Skipped...)
+	}
+	return merged
+}
+
+func carFiles(car *carfinder.Car) []string {
+	var files []string
+	files = append(files, car.StreamFiles...)
+	files = append(files, car.DataFiles...)
+	return append(files, car.AudioFiles...)
+}
+
+// removeShadowed drops the files that lost to a higher priority input path and records them as skipped.
+func removeShadowed(result *scanner.Result, lost map[string]string) {
+	if len(lost) == 0 {
+		return
+	}
+	skip := func(path string) bool {
+		winner, ok := lost[path]
+		if ok {
+			result.Skipped = append(result.Skipped, dft.SkippedFile{Path: path, Reason: fmt.Sprintf("shadowed by %s from an input path with a higher priority", winner)})
+		}
+		return ok
+	}
+
+	var streamFiles []dft.StreamFile
+	for _, file := range result.StreamFiles {
+		if !skip(file.Path) {
+			streamFiles = append(streamFiles, file)
+		}
+	}
+	var dataFiles []dft.DataFile
+	for _, file := range result.DataFiles {
+		if !skip(file.Path) {
+			dataFiles = append(dataFiles, file)
+		}
+	}
+	var audioFiles []dft.AudioFile
+	for _, file := range result.AudioFiles {
+		if !skip(file.Path) {
+			audioFiles = append(audioFiles, file)
+		}
+	}
+	result.StreamFiles, result.DataFiles, result.AudioFiles = streamFiles, dataFiles, audioFiles
+}
@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"io"
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+)
+
+const LogFile = "merger.log"
+
+var output io.Writer = os.Stderr
+
+// Open recreates the log file and mirrors all log output to console and the file.
+func Open(console io.Writer) (*os.File, error) {
+	// Clear existing log file by recreating it
+	f, err := os.OpenFile(LogFile, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return nil, err
+	}
+	output = io.MultiWriter(console, f)
+	log.SetOutput(output)
+	return f, nil
+}
+
+// Output returns the writer Open set up, so log output can be restored after redirecting it.
+func Output() io.Writer {
+	return output
+}
+
+func Configure(verbose bool) {
+	log.SetFormatter(log.TextFormatter)
+	log.SetReportCaller(true)
+	log.SetPrefix(prefix())
+	log.SetLevel(level(verbose))
+}
+
+// New returns a logger that writes to w and looks like the one Configure sets up, for output
+// that has to stay apart from the default logger.
+func New(w io.Writer, verbose bool) *log.Logger {
+	return log.NewWithOptions(w, log.Options{
+		Formatter:       log.TextFormatter,
+		ReportCaller:    true,
+		ReportTimestamp: true,
+		Prefix:          prefix(),
+		Level:           level(verbose),
+	})
+}
+
+func prefix() string {
+	style := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#ff7df9"))
+	return style.Render("FiveMCarsMerger")
+}
+
+func level(verbose bool) log.Level {
+	if verbose {
+		return log.DebugLevel
+	}
+	return log.InfoLevel
+}
@@ -2,7 +2,7 @@ package manifestgen
 
 import (
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
@@ -24,122 +24,215 @@ type Manifest struct {
 	HasVehicles         bool
 	HasWeaponsFile      bool
 	HasAudio            bool
-	AudioConfigs        []string // Store unique audio config names
-	AudioWavePacks      []string // Store unique wavepack folder names
+	AudioConfigs        []AudioConfig   // Store unique audio configs
+	AudioWavePacks      []AudioWavePack // Store unique wavepack folders
+}
+
+// AudioConfig is one engine's audio data in audioconfig/.
+type AudioConfig struct {
+	Name      string
+	Files     []string
+	HasGame   bool
+	HasSounds bool
+}
+
+// AudioWavePack is one sfx/dlc_<name> folder and the file patterns it holds.
+type AudioWavePack struct {
+	Name     string
+	Patterns []string
 }
 
 type Generator interface {
 	Generate() error
+	Render(paths []string) (string, error)
+	SetLogger(logger *log.Logger)
 }
 
 type generator struct {
-	Flags flags.Flags
+	Flags  flags.Flags
+	Logger *log.Logger
 }
 
 func New(_flags flags.Flags) Generator {
-	return &generator{Flags: _flags}
+	return &generator{Flags: _flags, Logger: log.Default()}
+}
+
+// SetLogger makes the generator log to logger instead of the default logger.
+func (g *generator) SetLogger(logger *log.Logger) {
+	g.Logger = logger
 }
 
 func (g *generator) Generate() error {
-	log.Debug("Starting manifest generation")
+	g.Logger.Debug("Starting manifest generation")
 
+	// Check if data directory exists
+	dataPath := filepath.Join(g.Flags.OutputPath, "data")
+	if _, err := os.Stat(dataPath); err != nil {
+		return fmt.Errorf("failed to read data directory: %w", err)
+	}
+
+	var paths []string
+	for _, dir := range []string{"data", "audioconfig", "sfx"} {
+		root := filepath.Join(g.Flags.OutputPath, dir)
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+			if err != nil || f.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(g.Flags.OutputPath, path)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, filepath.ToSlash(rel))
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read %s directory: %w", dir, err)
+		}
+	}
+
+	// Create manifest file
+	manifestPath := filepath.Join(g.Flags.OutputPath, "fxmanifest.lua")
+	g.Logger.Debug("Creating manifest file", "path", manifestPath)
+
+	fxManifest, err := os.Create(manifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest file: %w", err)
+	}
+	defer fxManifest.Close()
+
+	if err := execute(fxManifest, g.newManifest(paths)); err != nil {
+		return err
+	}
+
+	g.Logger.Info("Successfully generated fxmanifest.lua")
+	return nil
+
+}
+
+// Render returns the fxmanifest.lua that Generate would write for an output
+// containing the given paths, which are relative to the output path.
+func (g *generator) Render(paths []string) (string, error) {
+	var rendered strings.Builder
+	if err := execute(&rendered, g.newManifest(paths)); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
+
+func execute(w io.Writer, manifest Manifest) error {
 	tmpl, err := template.New("manifestTemplate").Parse(manifestTemplate)
 	if err != nil {
 		return fmt.Errorf("failed to parse template: %w", err)
 	}
 
+	// Execute template
+	if err := tmpl.Execute(w, manifest); err != nil {
+		return fmt.Errorf("failed to execute template: %w", err)
+	}
+	return nil
+}
+
+func (g *generator) newManifest(paths []string) Manifest {
 	manifest := Manifest{
-		AudioConfigs:   make([]string, 0),
-		AudioWavePacks: make([]string, 0),
+		AudioConfigs:   make([]AudioConfig, 0),
+		AudioWavePacks: make([]AudioWavePack, 0),
 	}
 
-	// Check if data directory exists
-	dataPath := filepath.Join(g.Flags.OutputPath, "data")
-	folders, err := ioutil.ReadDir(dataPath)
-	if err != nil {
-		return fmt.Errorf("failed to read data directory: %w", err)
+	var dataFolders []string
+	audioConfigs := make(map[string]*AudioConfig)
+	wavePacks := make(map[string]*AudioWavePack)
+
+	for _, p := range paths {
+		parts := strings.Split(filepath.ToSlash(p), "/")
+		switch {
+		case len(parts) == 3 && parts[0] == "data":
+			dataFolders = append(dataFolders, parts[1])
+		case len(parts) == 2 && parts[0] == "audioconfig":
+			fileName := parts[1]
+			// Example: hondaf20c_game.dat151.rel -> hondaf20c
+			if !strings.Contains(fileName, "_game") && !strings.Contains(fileName, "_sounds") {
+				continue
+			}
+			engineName := strings.Split(fileName, "_")[0]
+			config, ok := audioConfigs[engineName]
+			if !ok {
+				config = &AudioConfig{Name: engineName}
+				audioConfigs[engineName] = config
+			}
+			config.Files = append(config.Files, fileName)
+			config.HasGame = config.HasGame || strings.Contains(fileName, "_game.dat")
+			config.HasSounds = config.HasSounds || strings.Contains(fileName, "_sounds.dat")
+			g.Logger.Debug("Found audio config", "engine", engineName, "file", fileName)
+		case len(parts) == 3 && parts[0] == "sfx" && strings.HasPrefix(parts[1], "dlc_"):
+			packName := strings.TrimPrefix(parts[1], "dlc_")
+			pack, ok := wavePacks[packName]
+			if !ok {
+				pack = &AudioWavePack{Name: packName}
+				wavePacks[packName] = pack
+			}
+			pattern := "*" + filepath.Ext(parts[2])
+			if !containsString(pack.Patterns, pattern) {
+				pack.Patterns = append(pack.Patterns, pattern)
+			}
+			g.Logger.Debug("Found audio wave pack", "pack", packName, "file", parts[2])
+		}
 	}
 
-	log.Debug("Processing data folders", "count", len(folders))
+	g.Logger.Debug("Processing data folders", "count", len(dataFolders))
 
 	// Process data folders
-	for _, folder := range folders {
-		folderName := strings.ToLower(folder.Name())
+	for _, folder := range dataFolders {
+		folderName := strings.ToLower(folder)
 		switch folderName {
 		case strings.ToLower(dft.CARCOLS.String()):
 			manifest.HasCarcols = true
-			log.Debug("Found carcols folder", "folder", folderName)
 		case strings.ToLower(dft.CARVARIATIONS.String()):
 			manifest.HasCarvariations = true
-			log.Debug("Found carvariations folder", "folder", folderName)
 		case strings.ToLower(dft.CONTENTUNLOCKS.String()):
 			manifest.HasContentUnlocks = true
-			log.Debug("Found contentunlocks folder", "folder", folderName)
 		case strings.ToLower(dft.HANDLING.String()):
 			manifest.HasHandling = true
-			log.Debug("Found handling folder", "folder", folderName)
 		case strings.ToLower(dft.VEHICLELAYOUTS.String()):
 			manifest.HasVehicleLayouts = true
-			log.Debug("Found vehiclelayouts folder", "folder", folderName)
 		case strings.ToLower(dft.VEHICLEMODELSETS.String()):
 			manifest.HasVehicleModelsets = true
-			log.Debug("Found vehiclemodelsets folder", "folder", folderName)
 		case strings.ToLower(dft.VEHICLES.String()):
 			manifest.HasVehicles = true
-			log.Debug("Found vehicles folder", "folder", folderName)
 		case strings.ToLower(dft.WEAPONSFILE.String()):
 			manifest.HasWeaponsFile = true
-			log.Debug("Found weaponsfile folder", "folder", folderName)
 		default:
-			log.Debug("Skipping unknown folder", "folder", folderName)
+			g.Logger.Debug("Skipping unknown folder", "folder", folderName)
 		}
 	}
 
-	// Process audio files if they exist
-	// Process audio config files
-	audioConfigPath := filepath.Join(g.Flags.OutputPath, "audioconfig")
-	if _, err := os.Stat(audioConfigPath); err == nil {
-		manifest.HasAudio = true
-		audioFiles, _ := ioutil.ReadDir(audioConfigPath)
-
-		uniqueConfigs := make(map[string]struct{})
-
-		for _, file := range audioFiles {
-			fileName := file.Name()
-			// Example: hondaf20c_game.dat151.rel -> hondaf20c
-			if strings.Contains(fileName, "_game") || strings.Contains(fileName, "_sounds") {
-				parts := strings.Split(fileName, "_")
-				if len(parts) > 0 {
-					engineName := parts[0]
-					uniqueConfigs[engineName] = struct{}{}
-					log.Debug("Found audio config", "engine", engineName, "file", fileName)
-				}
-			}
-		}
-
-		// Convert to sorted slice for consistent output
-		for config := range uniqueConfigs {
-			manifest.AudioConfigs = append(manifest.AudioConfigs, config)
-		}
-		sort.Strings(manifest.AudioConfigs)
-	}
-
-	// Create manifest file
-	manifestPath := filepath.Join(g.Flags.OutputPath, "fxmanifest.lua")
-	log.Debug("Creating manifest file", "path", manifestPath)
-
-	fxManifest, err := os.Create(manifestPath)
-	if err != nil {
-		return fmt.Errorf("failed to create manifest file: %w", err)
+	// Convert to sorted slices for consistent output
+	for _, config := range audioConfigs {
+		sort.Strings(config.Files)
+		manifest.AudioConfigs = append(manifest.AudioConfigs, *config)
 	}
-	defer fxManifest.Close()
-
-	// Execute template
-	if err := tmpl.Execute(fxManifest, manifest); err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
+	sort.Slice(manifest.AudioConfigs, func(i, j int) bool {
+		return manifest.AudioConfigs[i].Name < manifest.AudioConfigs[j].Name
+	})
+	for _, pack := range wavePacks {
+		sort.Strings(pack.Patterns)
+		manifest.AudioWavePacks = append(manifest.AudioWavePacks, *pack)
 	}
+	sort.Slice(manifest.AudioWavePacks, func(i, j int) bool {
+		return manifest.AudioWavePacks[i].Name < manifest.AudioWavePacks[j].Name
+	})
+	manifest.HasAudio = len(manifest.AudioConfigs) > 0 || len(manifest.AudioWavePacks) > 0
 
-	log.Info("Successfully generated fxmanifest.lua")
-	return nil
+	return manifest
+}
 
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,122 @@
+package manifestgen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		paths   []string
+		want    []string // lines of the manifest
+		without []string // substrings the manifest must not contain
+	}{
+		{
+			name:    "data folders",
+			paths:   []string{"data/handling/handling_adder.meta", "data/vehicles/vehicles_adder.meta", "data/carvariations/carvariations_adder.meta"},
+			want:    []string{"'data/handling/*.meta',", "data_file 'HANDLING_FILE' 'data/handling/*.meta'", "data_file 'VEHICLE_METADATA_FILE' 'data/vehicles/*.meta'", "data_file 'VEHICLE_VARIATION_FILE' 'data/carvariations/*.meta'"},
+			without: []string{"vehiclevariations", "carcols", "audioconfig", "AUDIO_"},
+		},
+		{
+			name:    "unknown data folders and nested files are skipped",
+			paths:   []string{"data/peds/peds.meta", "data/handling/extra/handling.meta"},
+			without: []string{"data_file"},
+		},
+		{
+			name: "audio configs list only existing files",
+			paths: []string{
+				"audioconfig/v8a_game.dat151.rel",
+				"audioconfig/v8a_game.dat151.nametable",
+				"audioconfig/readme.txt",
+			},
+			want: []string{
+				"'audioconfig/v8a_game.dat151.nametable',",
+				"'audioconfig/v8a_game.dat151.rel',",
+				"data_file 'AUDIO_GAMEDATA' 'audioconfig/v8a_game.dat'",
+			},
+			without: []string{"_sounds", "AUDIO_SOUNDDATA", "readme.txt", "AUDIO_WAVEPACK"},
+		},
+		{
+			name: "audio configs with game and sounds data",
+			paths: []string{
+				"audioconfig/v8a_game.dat151.rel",
+				"audioconfig/v8a_sounds.dat54.rel",
+				"audioconfig/i4_sounds.dat54.rel",
+			},
+			want: []string{
+				"data_file 'AUDIO_GAMEDATA' 'audioconfig/v8a_game.dat'",
+				"data_file 'AUDIO_SOUNDDATA' 'audioconfig/v8a_sounds.dat'",
+				"data_file 'AUDIO_SOUNDDATA' 'audioconfig/i4_sounds.dat'",
+			},
+			without: []string{"'audioconfig/i4_game.dat'"},
+		},
+		{
+			name:    "wave packs by the extensions they hold",
+			paths:   []string{"sfx/dlc_v8a/v8a.awc", "sfx/dlc_v8a/v8a_npc.awc", "sfx/readme.txt"},
+			want:    []string{"'sfx/dlc_v8a/*.awc',", "data_file 'AUDIO_WAVEPACK' 'sfx/dlc_v8a'"},
+			without: []string{"*.awc2", "readme.txt", "AUDIO_GAMEDATA"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manifest, err := New(flags.Flags{}).Render(test.paths)
+			if err != nil {
+				t.Fatalf("Render() = %v", err)
+			}
+			lines := make(map[string]bool)
+			for _, line := range strings.Split(manifest, "\n") {
+				lines[strings.TrimSpace(line)] = true
+			}
+			for _, want := range test.want {
+				if !lines[want] {
+					t.Errorf("manifest has no line %q:\n%s", want, manifest)
+				}
+			}
+			for _, without := range test.without {
+				if strings.Contains(manifest, without) {
+					t.Errorf("manifest contains %q:\n%s", without, manifest)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	output := t.TempDir()
+	for _, path := range []string{"data/handling/handling_adder.meta", "audioconfig/v8a_game.dat151.rel", "sfx/dlc_v8a/v8a.awc", "stream/adder.yft"} {
+		full := filepath.Join(output, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := New(flags.Flags{OutputPath: output})
+	if err := g.Generate(); err != nil {
+		t.Fatalf("Generate() = %v", err)
+	}
+	written, err := os.ReadFile(filepath.Join(output, "fxmanifest.lua"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := g.Render([]string{"data/handling/handling_adder.meta", "audioconfig/v8a_game.dat151.rel", "sfx/dlc_v8a/v8a.awc", "stream/adder.yft"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != rendered {
+		t.Errorf("Generate() wrote\n%s\nwant what Render() returns\n%s", written, rendered)
+	}
+}
+
+func TestGenerateWithoutData(t *testing.T) {
+	if err := New(flags.Flags{OutputPath: t.TempDir()}).Generate(); err == nil {
+		t.Error("Generate() of an output without data = nil, want an error")
+	}
+}
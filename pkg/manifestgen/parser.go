@@ -0,0 +1,49 @@
+package manifestgen
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+type ParsedManifest struct {
+	Files     []string
+	DataFiles []DataFileEntry
+}
+
+type DataFileEntry struct {
+	Type string
+	Path string
+}
+
+var (
+	commentPattern  = regexp.MustCompile(`--[^\n]*`)
+	filesPattern    = regexp.MustCompile(`(?s)\bfiles\s*\(?\s*\{(.*?)\}`)
+	stringPattern   = regexp.MustCompile(`['"]([^'"]+)['"]`)
+	dataFilePattern = regexp.MustCompile(`\bdata_file\s*\(?\s*['"]([^'"]+)['"]\s*\)?\s*\(?\s*['"]([^'"]+)['"]`)
+)
+
+// ParseManifest reads the files block and data_file entries of an fxmanifest.lua.
+// It understands the manifests written by Generate and the common hand written forms.
+func ParseManifest(path string) (*ParsedManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	source := commentPattern.ReplaceAllString(string(data), "")
+
+	manifest := &ParsedManifest{}
+	for _, block := range filesPattern.FindAllStringSubmatch(source, -1) {
+		for _, entry := range stringPattern.FindAllStringSubmatch(block[1], -1) {
+			manifest.Files = append(manifest.Files, strings.TrimSpace(entry[1]))
+		}
+	}
+	for _, entry := range dataFilePattern.FindAllStringSubmatch(source, -1) {
+		manifest.DataFiles = append(manifest.DataFiles, DataFileEntry{
+			Type: entry[1],
+			Path: strings.TrimSpace(entry[2]),
+		})
+	}
+
+	return manifest, nil
+}
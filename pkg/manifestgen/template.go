@@ -29,15 +29,15 @@ files {
     'data/weaponsfile/*.meta',
     {{ end -}}
     {{ if .HasAudio -}}
-    {{ range $name := .AudioConfigs -}}
-    'audioconfig/{{ $name }}_game.dat151.rel',
-    'audioconfig/{{ $name }}_game.dat151.nametable',
-    'audioconfig/{{ $name }}_sounds.dat54.rel',
-    'audioconfig/{{ $name }}_sounds.dat54.nametable',
+    {{ range $config := .AudioConfigs -}}
+    {{ range $file := $config.Files -}}
+    'audioconfig/{{ $file }}',
+    {{ end -}}
     {{ end -}}
     {{ range $pack := .AudioWavePacks -}}
-    'sfx/dlc_{{ $pack }}/*.awc',
-    'sfx/dlc_{{ $pack }}/*.awc2',
+    {{ range $pattern := $pack.Patterns -}}
+    'sfx/dlc_{{ $pack.Name }}/{{ $pattern }}',
+    {{ end -}}
     {{ end -}}
     {{ end }}
 }
@@ -46,7 +46,7 @@ files {
 data_file 'CARCOLS_FILE' 'data/carcols/*.meta'
 {{ end -}}
 {{ if .HasCarvariations -}}
-data_file 'VEHICLE_VARIATION_FILE' 'data/vehiclevariations/*.meta'
+data_file 'VEHICLE_VARIATION_FILE' 'data/carvariations/*.meta'
 {{ end -}}
 {{ if .HasContentUnlocks -}}
 data_file 'CONTENT_UNLOCKING_META_FILE' 'data/contentunlocks/*.meta'
@@ -67,11 +67,15 @@ data_file 'VEHICLE_METADATA_FILE' 'data/vehicles/*.meta'
 data_file 'WEAPONINFO_FILE' 'data/weaponsfile/*.meta'
 {{ end -}}
 {{ if .HasAudio -}}
-{{ range $name := .AudioConfigs -}}
-data_file 'AUDIO_GAMEDATA' 'audioconfig/{{ $name }}_game.dat'
-data_file 'AUDIO_SOUNDDATA' 'audioconfig/{{ $name }}_sounds.dat'
+{{ range $config := .AudioConfigs -}}
+{{ if $config.HasGame -}}
+data_file 'AUDIO_GAMEDATA' 'audioconfig/{{ $config.Name }}_game.dat'
+{{ end -}}
+{{ if $config.HasSounds -}}
+data_file 'AUDIO_SOUNDDATA' 'audioconfig/{{ $config.Name }}_sounds.dat'
+{{ end -}}
 {{ end -}}
 {{ range $pack := .AudioWavePacks -}}
-data_file 'AUDIO_WAVEPACK' 'sfx/dlc_{{ $pack }}'
+data_file 'AUDIO_WAVEPACK' 'sfx/dlc_{{ $pack.Name }}'
 {{ end -}}
 {{ end }}`
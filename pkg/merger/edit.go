@@ -0,0 +1,170 @@
+package merger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/carfinder"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/dft"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/exitcode"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/plan"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/scanner"
+	sliceutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/slice"
+)
+
+// Add copies the car in carPath into the existing output and regenerates the manifest.
+// It refuses to add a model the output already has or to overwrite any file.
+func (m *merger) Add(carPath string) error {
+	if _, err := os.Stat(filepath.Join(m.Flags.OutputPath, "data")); err != nil {
+		return fmt.Errorf("%s is not a merged resource: %w", m.Flags.OutputPath, err)
+	}
+
+	carFlags := m.Flags
+	carFlags.InputPath = carPath
+	result, err := scanner.New(carFlags).Scan()
+	if err != nil {
+		return err
+	}
+
+	var models []string
+	var vehiclesFiles []string
+	for _, dataFile := range result.DataFiles {
+		if dataFile.Type != dft.VEHICLES {
+			continue
+		}
+		modelNames, err := carfinder.ReadModelNames(dataFile.Path)
+		if err != nil {
+			return err
+		}
+		models = append(models, modelNames...)
+		vehiclesFiles = append(vehiclesFiles, dataFile.Path)
+	}
+	if len(models) == 0 {
+		return exitcode.Validationf("no vehicles.meta with a model name found in %s", carPath)
+	}
+
+	existingCars, err := m.CarFinder.FindDataFileCars()
+	if err != nil {
+		return err
+	}
+	for _, model := range models {
+		if sliceutils.ContainsElement(existingCars, model) {
+			return exitcode.Validationf("car %s already exists in %s", model, m.Flags.OutputPath)
+		}
+	}
+
+	var ops []plan.Operation
+	ops = append(ops, m.Copier.PlanAudioFiles(result.AudioFiles)...)
+	ops = append(ops, m.Copier.PlanStreamFiles(result.StreamFiles)...)
+	dataOps, _ := m.Copier.PlanDataFiles(result.DataFiles)
+	ops = append(ops, dataOps...)
+	for _, op := range ops {
+		if _, err := os.Stat(filepath.Join(m.Flags.OutputPath, filepath.FromSlash(op.Destination))); err == nil {
+			return exitcode.Validationf("adding %s would overwrite %s", op.Source, op.Destination)
+		}
+	}
+
+	m.Logger.Info("Adding cars", "cars", sliceutils.RemoveDuplicates(models), "files", len(ops))
+	if err := m.Copier.Apply(context.Background(), ops, nil); err != nil {
+		return err
+	}
+	m.recordHashes(vehiclesFiles)
+
+	m.Logger.Info("Generating fxmanifest.lua")
+	return m.Generator.Generate()
+}
+
+// Remove deletes every file of a car from the existing output and regenerates the manifest.
+// Audio packs that another car still uses are kept. A car whose vehicles.meta declares other
+// models too is refused, since their stream files are grouped under it.
+func (m *merger) Remove(model string) error {
+	model = strings.ToLower(model)
+	cars, _, err := carfinder.ResourceCars(m.Flags.OutputPath)
+	if err != nil {
+		return fmt.Errorf("%s is not a merged resource: %w", m.Flags.OutputPath, err)
+	}
+
+	var car *carfinder.Car
+	for _, candidate := range cars {
+		if candidate.Model == model {
+			car = candidate
+		}
+	}
+	for _, candidate := range cars {
+		others, err := otherModels(candidate)
+		if err != nil {
+			return err
+		}
+		switch {
+		case candidate == car && len(others) > 0:
+			return exitcode.Validationf("the vehicles.meta of %s also declares %s, remove them together by re-merging without them", model, strings.Join(others, ", "))
+		case car == nil && sliceutils.ContainsElement(others, model):
+			return exitcode.Validationf("%s is declared in the vehicles.meta of %s, remove them together by re-merging without them", model, candidate.Model)
+		}
+	}
+	if car == nil {
+		return exitcode.Validationf("car %s not found in %s", model, m.Flags.OutputPath)
+	}
+
+	files := append(append([]string{}, car.StreamFiles...), car.DataFiles...)
+	sharedAudio := false
+	for _, other := range cars {
+		if other != car && car.AudioName != "" && other.AudioName == car.AudioName {
+			sharedAudio = true
+		}
+	}
+	if sharedAudio {
+		m.Logger.Info("Keeping audio shared with other cars", "audio", car.AudioName)
+	} else {
+		files = append(files, car.AudioFiles...)
+	}
+
+	m.Logger.Info("Removing car", "car", car.Model, "files", len(files))
+	for _, file := range files {
+		m.Logger.Debug("Removing file", "path", file)
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+		removeEmptyParents(filepath.Dir(file), m.Flags.OutputPath)
+	}
+
+	m.Logger.Info("Generating fxmanifest.lua")
+	return m.Generator.Generate()
+}
+
+// otherModels returns the models besides car's own that its vehicles.meta files declare.
+func otherModels(car *carfinder.Car) ([]string, error) {
+	var others []string
+	for _, dataFile := range car.DataFiles {
+		if !strings.EqualFold(filepath.Base(filepath.Dir(dataFile)), dft.VEHICLES.String()) {
+			continue
+		}
+		vehicles, err := carfinder.ReadVehicles(dataFile)
+		if err != nil {
+			return nil, err
+		}
+		for _, vehicle := range vehicles {
+			if other := strings.ToLower(vehicle.ModelName); other != car.Model && !sliceutils.ContainsElement(others, other) {
+				others = append(others, other)
+			}
+		}
+	}
+	return others, nil
+}
+
+// removeEmptyParents removes dir and its parents up to, but not including, root while they are empty.
+func removeEmptyParents(dir string, root string) {
+	for dir != root && len(dir) > len(root) {
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) > 0 {
+			return
+		}
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
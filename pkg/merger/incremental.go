@@ -0,0 +1,87 @@
+package merger
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/cache"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/plan"
+)
+
+// loadCache returns the build cache of the output, or nil when every file has to be copied.
+func (m *merger) loadCache() *cache.Cache {
+	if m.Flags.Rebuild {
+		return nil
+	}
+	buildCache, err := cache.Load(m.Flags.OutputPath)
+	if err != nil {
+		m.report.Warn("Ignoring the build cache, every file is copied again", "err", err)
+		return nil
+	}
+	return buildCache
+}
+
+// pending returns the operations of kind whose output isn't up to date.
+func pending(p *plan.Plan, upToDate []bool, kind plan.Kind) []plan.Operation {
+	var ops []plan.Operation
+	for i, op := range p.Operations {
+		if op.Kind == kind && !upToDate[i] {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// removeStale deletes the files of the last merge that this merge doesn't write anymore.
+// With Clean, every other file that isn't part of this merge is deleted too, so the output
+// ends up like a clean build without copying the unchanged files again.
+func (m *merger) removeStale(p *plan.Plan, buildCache *cache.Cache) error {
+	stale := buildCache.Stale(p.Operations)
+	if m.Flags.Clean {
+		keep := map[string]bool{"fxmanifest.lua": true, cache.FileName: true}
+		for _, op := range p.Operations {
+			keep[op.Destination] = true
+		}
+		for _, destination := range stale {
+			keep[destination] = true
+		}
+		err := filepath.Walk(m.Flags.OutputPath, func(path string, f os.FileInfo, err error) error {
+			if err != nil || f.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(m.Flags.OutputPath, path)
+			if err != nil {
+				return err
+			}
+			if !keep[filepath.ToSlash(rel)] {
+				stale = append(stale, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		sort.Strings(stale)
+	}
+
+	for _, destination := range stale {
+		path := filepath.Join(m.Flags.OutputPath, filepath.FromSlash(destination))
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		m.Logger.Debug("Removed stale output", "path", path)
+		m.report.Deleted = append(m.report.Deleted, destination)
+
+		// Folders emptied by the removal go too
+		for dir := filepath.Dir(path); dir != m.Flags.OutputPath; dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+	if len(stale) > 0 {
+		m.Logger.Info("Removed outputs the merge doesn't write anymore", "files", len(stale))
+	}
+	return nil
+}
@@ -1,162 +1,496 @@
 package merger
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/cache"
 	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/carfinder"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/config"
 	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/copier"
 	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/dft"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/exitcode"
 	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/hash"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/inspector"
 	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/manifestgen"
-	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/typeidentifier"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/overrides"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/plan"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/report"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/scanner"
+	poolutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/pool"
 	sliceutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/slice"
-	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/validator"
 	"github.com/charmbracelet/log"
 )
 
+// Progress describes how far the current stage of a merge is.
+type Progress struct {
+	Stage string
+	Done  int
+	Total int
+}
+
 type Merger interface {
 	Merge() error
+	MergeContext(ctx context.Context) error
+	Plan() (*plan.Plan, error)
+	Apply(p *plan.Plan) error
+	ApplyContext(ctx context.Context, p *plan.Plan) error
+	OnProgress(fn func(Progress))
+	SetLogger(logger *log.Logger)
+	Add(carPath string) error
+	Remove(model string) error
+	Report() *report.Report
 }
 
 type merger struct {
-	Flags          flags.Flags
-	Generator      manifestgen.Generator
-	Validator      validator.Validator
-	TypeIdentifier typeidentifier.TypeIdentifier
-	CarFinder      carfinder.CarFinder
-	Copier         copier.Copier
+	Flags     flags.Flags
+	Generator manifestgen.Generator
+	Scanner   scanner.Scanner
+	CarFinder carfinder.CarFinder
+	Copier    copier.Copier
+	Logger    *log.Logger
+
+	report   *report.Report
+	progress func(Progress)
 }
 
 func New(_flags flags.Flags) Merger {
+	if outputPath, err := filepath.Abs(_flags.OutputPath); err == nil {
+		_flags.OutputPath = outputPath
+	}
 	return &merger{
-		Flags:          _flags,
-		Generator:      manifestgen.New(_flags),
-		Validator:      validator.New(),
-		TypeIdentifier: typeidentifier.New(),
-		CarFinder:      carfinder.New(_flags),
-		Copier:         copier.New(_flags),
+		Flags:     _flags,
+		Generator: manifestgen.New(_flags),
+		Scanner:   scanner.New(_flags),
+		CarFinder: carfinder.New(_flags),
+		Copier:    copier.New(_flags),
+		Logger:    log.Default(),
+	}
+}
+
+// Report returns the report of the last Merge or Apply.
+func (m *merger) Report() *report.Report {
+	return m.report
+}
+
+// OnProgress sets a function that is called whenever a merge makes progress.
+func (m *merger) OnProgress(fn func(Progress)) {
+	m.progress = fn
+}
+
+// SetLogger makes the merger and the parts it runs log to logger instead of the default
+// logger, e.g. to keep the output of one merge apart from everything else.
+func (m *merger) SetLogger(logger *log.Logger) {
+	m.Logger = logger
+	m.Generator.SetLogger(logger)
+	m.Scanner.SetLogger(logger)
+	m.Copier.SetLogger(logger)
+}
+
+func (m *merger) notify(stage string, done int, total int) {
+	if m.progress != nil {
+		m.progress(Progress{Stage: stage, Done: done, Total: total})
 	}
 }
 
 func (m *merger) Merge() error {
-	var streamFiles []dft.StreamFile
-	var dataFiles []dft.DataFile
-	var audioFiles []dft.AudioFile // New slice for audio files
+	return m.MergeContext(context.Background())
+}
 
-	log.Info("Creating Output Directory...")
-	outputPath, err := filepath.Abs(m.Flags.OutputPath)
+// MergeContext merges like Merge and stops between stages and files once ctx is done.
+func (m *merger) MergeContext(ctx context.Context) (err error) {
+	// Invalid paths fail before anything, even the report, is written
+	if err := config.CheckPaths(m.Flags); err != nil {
+		return err
+	}
+	m.report = report.New(m.Flags.InputPath, m.Flags.OutputPath)
+	m.report.SetLogger(m.Logger)
+	defer func() { err = m.saveReport(err) }()
+
+	var p *plan.Plan
+	m.notify("plan", 0, 1)
+	err = m.report.Time("plan", func() error {
+		p, err = m.Plan()
+		return err
+	})
 	if err != nil {
 		return err
 	}
-	m.Flags.OutputPath = outputPath
-	if err := m.CreateOutputDirectory(); err != nil {
+	m.notify("plan", 1, 1)
+
+	if len(p.OperationsOfKind(plan.DATA)) == 0 || len(p.OperationsOfKind(plan.STREAM)) == 0 {
+		m.report.AddPlan(p)
+		return exitcode.Validationf("cannot find any cars in %s", strings.Join(m.Flags.InputRoots(), ", "))
+	}
+
+	return m.apply(ctx, p)
+}
+
+// Plan walks the input paths and works out every operation a merge would run
+// without touching the output directory.
+func (m *merger) Plan() (*plan.Plan, error) {
+	if err := config.CheckPaths(m.Flags); err != nil {
+		return nil, err
+	}
+	roots := m.Flags.InputRoots()
+	m.Logger.Info("Identifying cars", "paths", roots)
+
+	overridesPath := m.overridesPath()
+	fingerprint, err := plan.Fingerprint(m.Flags, overridesPath)
+	if err != nil {
+		return nil, err
+	}
+
+	carOverrides, err := overrides.Load(overridesPath)
+	if err != nil {
+		return nil, err
+	}
+	scannedRoots, changes, shadowed, err := inspector.ScanRoots(m.Scanner, roots, carOverrides)
+	if err != nil {
+		return nil, err
+	}
+	result := inspector.Merge(scannedRoots)
+	// Warnings are kept on the plan, so merge and apply both add them to the report
+	var warnings []string
+	for _, warning := range changes.Warnings {
+		warnings = append(warnings, fmt.Sprintf("%s overrides=%s", warning, overridesPath))
+	}
+	for _, key := range changes.Unmatched(carOverrides) {
+		warnings = append(warnings, fmt.Sprintf("Override matches no folder or model of the input paths, key=%s overrides=%s", key, overridesPath))
+	}
+	for _, loser := range shadowed {
+		m.Logger.Info("Leaving out copy from an input path with a lower priority", "kind", loser.Kind, "name", loser.Name, "winner", loser.Winner, "loser", loser.Loser)
+	}
+
+	// Audio files are copied first, followed by stream and data files
+	var ops []plan.Operation
+	ops = append(ops, m.Copier.PlanAudioFiles(result.AudioFiles)...)
+	ops = append(ops, m.Copier.PlanStreamFiles(result.StreamFiles)...)
+	dataOps, skipped := m.Copier.PlanDataFiles(result.DataFiles)
+	ops = append(ops, dataOps...)
+	changes.RenameOperations(ops)
+
+	var destinations []string
+	for _, op := range ops {
+		destinations = append(destinations, op.Destination)
+	}
+	rendered, err := m.Generator.Render(destinations)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &plan.Plan{
+		OutputPath:  m.Flags.OutputPath,
+		Clean:       m.Flags.Clean,
+		Fingerprint: fingerprint,
+		Operations:  ops,
+		Manifest:    manifestEntries(rendered),
+		Skipped:     append(result.Skipped, skipped...),
+		Conflicts:   plan.FindConflicts(ops),
+		Overrides:   changes.Applied,
+		Shadowed:    shadowed,
+		Warnings:    warnings,
+	}
+	if len(roots) > 0 {
+		p.InputPath, p.ExtraInputs = roots[0], roots[1:]
+	}
+	p.Cars, p.NoStreamCars, p.NoDataCars = m.findPlannedCars(result)
+	p.Cars = changes.RenameCars(p.Cars)
+	p.NoStreamCars = changes.RenameCars(p.NoStreamCars)
+	p.NoDataCars = changes.RenameCars(p.NoDataCars)
+
+	for _, override := range p.Overrides {
+		m.Logger.Info("Applying override", "key", override.Key, "action", override.Action, "target", override.Target, "value", override.Value)
+	}
+
+	for _, skippedFile := range p.Skipped {
+		m.Logger.Debug("Skipping file", "path", skippedFile.Path, "reason", skippedFile.Reason)
+	}
+
+	return p, nil
+}
+
+// overridesPath returns the overrides file of the merge.
+func (m *merger) overridesPath() string {
+	if m.Flags.Overrides == "" {
+		return overrides.DefaultPath
+	}
+	return m.Flags.Overrides
+}
+
+// Apply runs a plan made by Plan. It refuses to run when the input paths, the overrides file
+// or the settings that change what is copied changed since the plan was made.
+func (m *merger) Apply(p *plan.Plan) error {
+	return m.ApplyContext(context.Background(), p)
+}
+
+// ApplyContext applies like Apply and stops between stages and files once ctx is done.
+func (m *merger) ApplyContext(ctx context.Context, p *plan.Plan) (err error) {
+	if err := config.CheckPaths(m.Flags); err != nil {
 		return err
 	}
+	m.report = report.New(p.InputPath, p.OutputPath)
+	m.report.SetLogger(m.Logger)
+	defer func() { err = m.saveReport(err) }()
 
-	log.Info("Identifying cars", "path", m.Flags.InputPath)
+	return m.apply(ctx, p)
+}
 
-	err = filepath.Walk(m.Flags.InputPath, func(path string, f os.FileInfo, err error) error {
-		if f.IsDir() {
-			return err
+func (m *merger) apply(ctx context.Context, p *plan.Plan) error {
+	m.report.AddPlan(p)
+	if m.Flags.Strict {
+		if problems := strictProblems(p); len(problems) > 0 {
+			return exitcode.Validationf("strict mode: %s", strings.Join(problems, "; "))
 		}
-		if m.Validator.IsValidAudioFile(f.Name()) || m.Validator.IsValidAudioDataFile(f.Name()) {
-			isConfig := m.Validator.IsValidAudioDataFile(f.Name())
-			dlcFolder := filepath.Base(filepath.Dir(path))
-			if strings.HasPrefix(dlcFolder, "dlc_") {
-				dlcFolder = strings.TrimPrefix(dlcFolder, "dlc_")
-			}
-
-			audioFiles = append(audioFiles, dft.AudioFile{
-				Path:      path,
-				Name:      f.Name(),
-				IsConfig:  isConfig,
-				DLCFolder: dlcFolder,
-			})
-			return err
+	}
+	for _, warning := range p.Warnings {
+		m.report.Warn(warning)
+	}
+	for _, conflict := range p.Conflicts {
+		m.report.Warn("Multiple files share one destination, the last one wins", "destination", conflict.Destination, "sources", conflict.Sources)
+	}
+	for _, skippedFile := range p.Skipped {
+		if skippedFile.UnknownTag != "" {
+			m.report.Warn("Skipping meta with unknown root tag", "path", skippedFile.Path, "tag", skippedFile.UnknownTag)
 		}
-		if m.Validator.IsValidStreamFile(f.Name()) {
-			streamFiles = append(streamFiles, dft.StreamFile{
-				Path: path,
-				Name: f.Name(),
-			})
-			return err
+	}
+
+	planFlags := m.Flags
+	planFlags.InputPath, planFlags.ExtraInputs = p.InputPath, p.ExtraInputs
+	fingerprint, err := plan.Fingerprint(planFlags, m.overridesPath())
+	if err != nil {
+		return err
+	}
+	if fingerprint != p.Fingerprint {
+		return fmt.Errorf("input paths %s, the overrides file or the include, exclude and excluded cars settings changed since the plan was made, create a new plan", strings.Join(p.InputRoots(), ", "))
+	}
+	if p.OutputPath != m.Flags.OutputPath {
+		return fmt.Errorf("plan writes to %s but the merger is configured for %s", p.OutputPath, m.Flags.OutputPath)
+	}
+	m.Flags.Clean = p.Clean
+	if m.Flags.Rebuild {
+		m.Flags.Clean = true
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	buildCache := m.loadCache()
+	var entries []cache.Entry
+	var upToDate []bool
+	err = m.report.Time("check", func() error {
+		entries, upToDate, err = buildCache.Check(m.Flags.OutputPath, p.Operations, poolutils.Workers(m.Flags.Concurrency))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	copied := 0
+	for i, unchanged := range upToDate {
+		m.report.Files[i].Unchanged = unchanged
+		if unchanged {
+			copied++
 		}
-		if m.Validator.IsValidDataFile(f.Name()) {
-			_type, err := m.TypeIdentifier.IdentifyDataFileType(path)
-			if err != nil {
+	}
+	onCopied := func(plan.Operation) {
+		copied++
+		m.notify("copy", copied, len(p.Operations))
+	}
+	err = m.report.Time("copy", func() error {
+		if buildCache != nil {
+			m.Logger.Info("Updating Output Directory...", "unchanged", copied, "changed", len(p.Operations)-copied)
+			if err := os.MkdirAll(m.Flags.OutputPath, 0755); err != nil {
 				return err
 			}
-			dataFile := dft.DataFile{
-				Path: path,
-				Name: f.Name(),
-				Type: _type,
+			if err := m.removeStale(p, buildCache); err != nil {
+				return err
+			}
+		} else {
+			m.Logger.Info("Creating Output Directory...")
+			if err := m.CreateOutputDirectory(); err != nil {
+				return err
 			}
+		}
+		m.notify("copy", copied, len(p.Operations))
 
-			if dataFile.Type != dft.INVALID {
-				dataFiles = append(dataFiles, dataFile)
+		if audioOps := pending(p, upToDate, plan.AUDIO); len(audioOps) > 0 {
+			m.Logger.Info("Copying Audio files...")
+			if err := m.Copier.Apply(ctx, audioOps, onCopied); err != nil {
+				return err
 			}
 		}
-		return err
+
+		m.Logger.Info("Copying Stream files...")
+		if err := m.Copier.Apply(ctx, pending(p, upToDate, plan.STREAM), onCopied); err != nil {
+			return err
+		}
+
+		m.Logger.Info("Copying Data files...")
+		return m.Copier.Apply(ctx, pending(p, upToDate, plan.DATA), onCopied)
 	})
 	if err != nil {
 		return err
 	}
 
-	if len(dataFiles) == 0 || len(streamFiles) == 0 {
-		log.Error("Cannot find any cars in the specified folder")
-
-		if err := m.Cleanup(); err != nil {
-			return err
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	err = m.report.Time("manifest", func() error {
+		if buildCache.SameManifest(m.Flags.OutputPath, p.Manifest) {
+			m.Logger.Info("fxmanifest.lua is up to date")
+			return nil
 		}
-		return nil
+		m.Logger.Info("Generating fxmanifest.lua")
+		return m.Generator.Generate()
+	})
+	if err != nil {
+		return err
 	}
 
-	// Add audio file copying after stream/data files
-	if len(audioFiles) > 0 {
-		log.Info("Copying Audio files...")
-		err = m.Copier.CopyAudioFilesToOutputDirectory(audioFiles)
+	err = m.report.Time("verify", func() error {
+		m.Logger.Info("Parsing Data Files For Cars...")
+		dataFileCars, err := m.CarFinder.FindDataFileCars()
+		if err != nil {
+			return err
+		}
+		m.Logger.Info("Parsing Stream Files For Cars...")
+		streamFileCars, err := m.CarFinder.FindStreamFileCars()
 		if err != nil {
 			return err
 		}
-	}
 
-	log.Info("Copying Stream files...")
-	err = m.Copier.CopyStreamFilesToOutputDirectory(streamFiles)
+		validCars, noStreamCars, noDataCars := carfinder.ClassifyCars(dataFileCars, streamFileCars)
+		m.report.Cars = sliceutils.RemoveDuplicates(validCars)
+		m.report.NoStreamCars = noStreamCars
+		m.report.NoDataCars = noDataCars
+		if len(noStreamCars) > 0 {
+			m.report.Warn("Following cars have no stream files", "cars", noStreamCars)
+		}
+		if len(noDataCars) > 0 {
+			m.report.Warn("Following cars have no data files", "cars", noDataCars)
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	log.Info("Copying Data files...")
-	err = m.Copier.CopyDataFilesToOutputDirectory(dataFiles)
-	if err != nil {
-		return err
+	var vehiclesFiles []string
+	for _, op := range p.OperationsOfKind(plan.DATA) {
+		if op.Type == dft.VEHICLES.String() {
+			vehiclesFiles = append(vehiclesFiles, filepath.Join(m.Flags.OutputPath, filepath.FromSlash(op.Destination)))
+		}
 	}
+	m.recordHashes(vehiclesFiles)
 
-	log.Info("Generating fxmanifest.lua")
-	err = m.Generator.Generate()
-	if err != nil {
-		return err
+	if err := cache.New(p.Manifest, p.Operations, entries).Save(m.Flags.OutputPath); err != nil {
+		m.report.Warn("Failed to write the build cache, the next merge copies every file again", "err", err)
 	}
 
-	log.Info("Parsing Data Files For Cars...")
-	dataFileCars, err := m.CarFinder.FindDataFileCars()
-	if err != nil {
-		return err
+	m.Logger.Info("Valid cars in the car pack", "cars", m.report.Cars)
+	m.Logger.Info("Success! Resource ready", "output_folder", m.Flags.OutputPath)
+
+	return nil
+}
+
+// strictProblems lists everything in a plan that strict mode turns into a failure.
+func strictProblems(p *plan.Plan) []string {
+	var problems []string
+	if len(p.NoStreamCars) > 0 {
+		problems = append(problems, fmt.Sprintf("cars without stream files: %s", strings.Join(p.NoStreamCars, ", ")))
 	}
-	log.Info("Parsing Stream Files For Cars...")
-	streamFileCars, err := m.CarFinder.FindStreamFileCars()
+	if len(p.NoDataCars) > 0 {
+		problems = append(problems, fmt.Sprintf("cars without data files: %s", strings.Join(p.NoDataCars, ", ")))
+	}
+	for _, skippedFile := range p.Skipped {
+		if skippedFile.UnknownTag != "" {
+			problems = append(problems, fmt.Sprintf("unknown root tag %s in %s", skippedFile.UnknownTag, skippedFile.Path))
+		}
+	}
+	for _, conflict := range p.Conflicts {
+		problems = append(problems, fmt.Sprintf("%d files copied to %s", len(conflict.Sources), conflict.Destination))
+	}
+	return problems
+}
+
+// recordHashes adds the model, handling and audio names of the vehicles.meta files to the
+// hash dictionary, so `hash lookup` can tell which car a hash from a server log belongs to.
+func (m *merger) recordHashes(vehiclesFiles []string) {
+	err := hash.Update(func(dictionary *hash.Dictionary) {
+		for _, path := range vehiclesFiles {
+			vehicles, err := carfinder.ReadVehicles(path)
+			if err != nil {
+				m.Logger.Debug("Failed to read vehicles.meta for the hash dictionary", "path", path, "err", err)
+				continue
+			}
+			for _, vehicle := range vehicles {
+				dictionary.Add(hash.MODEL, vehicle.ModelName, vehicle.ModelName)
+				dictionary.Add(hash.HANDLING, vehicle.HandlingID, vehicle.ModelName)
+				dictionary.Add(hash.AUDIO, vehicle.AudioNameHash, vehicle.ModelName)
+			}
+		}
+	})
 	if err != nil {
+		m.Logger.Warn("Failed to update the hash dictionary", "path", hash.DictionaryPath, "err", err)
+	}
+}
+
+// saveReport writes the report next to the output directory and returns err,
+// or the error from saving when the merge itself succeeded.
+func (m *merger) saveReport(err error) error {
+	m.report.Finish(err)
+
+	reportPath := report.PathFor(m.report.OutputPath)
+	if saveErr := m.report.Save(reportPath); saveErr != nil {
+		m.Logger.Error("Failed to write report", "path", reportPath, "err", saveErr)
+		if err == nil {
+			return saveErr
+		}
 		return err
 	}
+	m.Logger.Info("Report written", "path", reportPath)
+	return err
+}
 
-	validCars := sliceutils.RemoveDuplicates(m.CarFinder.FindValidCars(dataFileCars, streamFileCars))
+// findPlannedCars runs the car detection on the source files instead of the output.
+func (m *merger) findPlannedCars(result *scanner.Result) (validCars []string, noStreamCars []string, noDataCars []string) {
+	var dataFileCars, streamFileCars []string
+	for _, dataFile := range result.DataFiles {
+		if dataFile.Type != dft.VEHICLES {
+			continue
+		}
+		modelNames, err := carfinder.ReadModelNames(dataFile.Path)
+		if err != nil {
+			m.Logger.Debug("Failed to read vehicles.meta", "path", dataFile.Path)
+			continue
+		}
+		dataFileCars = append(dataFileCars, modelNames...)
+	}
+	for _, streamFile := range result.StreamFiles {
+		if car, ok := carfinder.StreamFileCar(streamFile.Name); ok {
+			streamFileCars = append(streamFileCars, car)
+		}
+	}
 
-	log.Info("Valid cars in the car pack", "cars", validCars)
-	log.Info("Success! Resource ready", "output_folder", m.Flags.OutputPath)
+	validCars, noStreamCars, noDataCars = carfinder.ClassifyCars(dataFileCars, streamFileCars)
+	return sliceutils.RemoveDuplicates(validCars), noStreamCars, noDataCars
+}
 
-	return nil
+// manifestEntries keeps the files and data_file lines of a rendered fxmanifest.lua.
+func manifestEntries(rendered string) []string {
+	var entries []string
+	for _, line := range strings.Split(rendered, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "'") || strings.HasPrefix(line, "data_file") {
+			entries = append(entries, strings.TrimSuffix(line, ","))
+		}
+	}
+	return entries
 }
 
 func (m *merger) CreateOutputDirectory() error {
@@ -0,0 +1,421 @@
+package overrides
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/carfinder"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/dft"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/plan"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/scanner"
+)
+
+// FileName is the name of the overrides file, which is kept next to config.json.
+const FileName = "overrides.json"
+
+// DefaultPath is the overrides file used when the Overrides setting is empty. Callers point
+// it next to the config file they use.
+var DefaultPath = FileName
+
+// Actions of the overrides recorded in plans and reports.
+const (
+	EXCLUDE  = "exclude"
+	RENAME   = "rename"
+	TYPE     = "type"
+	CATEGORY = "category"
+)
+
+var modelName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Override changes how one car is merged.
+type Override struct {
+	Exclude   bool              `json:",omitempty"`
+	Rename    string            `json:",omitempty"` // new spawn code
+	Category  string            `json:",omitempty"`
+	DataTypes map[string]string `json:",omitempty"` // file path relative to the car folder -> data file type
+}
+
+// Overrides maps a top level folder of the input path or a model name to its override.
+type Overrides map[string]*Override
+
+// Load reads the overrides file at path. A missing file has no overrides.
+func Load(path string) (Overrides, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var overrides Overrides
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&overrides); err != nil {
+		return nil, fmt.Errorf("invalid overrides %s: %w", path, err)
+	}
+	if err := overrides.validate(); err != nil {
+		return nil, fmt.Errorf("invalid overrides %s: %w", path, err)
+	}
+	return overrides, nil
+}
+
+func (o Overrides) validate() error {
+	for key, override := range o {
+		if key == "" || override == nil {
+			return fmt.Errorf("override %q is empty", key)
+		}
+		if override.Rename != "" && !modelName.MatchString(override.Rename) {
+			return fmt.Errorf("override %q renames to %q, spawn codes may only contain letters, digits and underscores", key, override.Rename)
+		}
+		if override.Exclude && override.Rename != "" {
+			return fmt.Errorf("override %q both excludes and renames the car", key)
+		}
+		for file, name := range override.DataTypes {
+			if _, ok := dft.ParseDataFileType(name); !ok {
+				return fmt.Errorf("override %q sets unknown data file type %q for %s", key, name, file)
+			}
+		}
+	}
+	return nil
+}
+
+// Changes are the overrides that matched scanned input trees. Exclusions and data file
+// types are applied to the scan result right away, renames once the copy is planned.
+type Changes struct {
+	Applied  []plan.Override
+	Warnings []string
+
+	renames []rename
+	matched map[string]bool
+}
+
+type rename struct {
+	from  string
+	to    string
+	files map[string]bool
+}
+
+// Apply matches every override against the top level folders and cars of result and
+// applies the exclusions and forced data file types to it.
+func (o Overrides) Apply(root string, result *scanner.Result) *Changes {
+	changes := &Changes{matched: make(map[string]bool)}
+	if len(o) == 0 {
+		return changes
+	}
+
+	cars, _ := carfinder.GroupCars(root, result.StreamFiles, result.DataFiles, result.AudioFiles)
+	owners := make(map[string]int)
+	for _, car := range cars {
+		for _, file := range carFiles(car) {
+			owners[file]++
+		}
+	}
+	folders := topLevelFolders(root, result)
+
+	keys := make([]string, 0, len(o))
+	for key := range o {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	excluded := make(map[string]string)
+	for _, key := range keys {
+		override := o[key]
+
+		// A key is a folder when one exists, otherwise the model name of cars anywhere
+		var matched []*carfinder.Car
+		var carFolders []string
+		folder := findFolder(folders, key)
+		for _, car := range cars {
+			if (folder != "" && car.Folder == folder) || (folder == "" && strings.EqualFold(car.Model, key)) {
+				matched = append(matched, car)
+				if !containsFold(carFolders, car.Folder) {
+					carFolders = append(carFolders, car.Folder)
+				}
+			}
+		}
+		if folder != "" {
+			carFolders = []string{folder}
+		}
+		if len(carFolders) == 0 {
+			continue
+		}
+		changes.matched[key] = true
+
+		for _, file := range sortedKeys(override.DataTypes) {
+			_type, _ := dft.ParseDataFileType(override.DataTypes[file])
+			for _, carFolder := range carFolders {
+				target := filepath.Join(root, carFolder, filepath.FromSlash(file))
+				if !forceType(result, target, _type) {
+					changes.warn("Override sets the type of a file that is not in the input path, key=%s file=%s", key, target)
+					continue
+				}
+				changes.Applied = append(changes.Applied, plan.Override{Key: key, Action: TYPE, Target: target, Value: _type.String()})
+			}
+		}
+
+		if override.Exclude {
+			if folder != "" {
+				for _, file := range folderFiles(root, folder, result) {
+					excluded[file] = key
+				}
+				changes.Applied = append(changes.Applied, plan.Override{Key: key, Action: EXCLUDE, Target: folder})
+			}
+			for _, car := range matched {
+				for _, file := range carFiles(car) {
+					// Files shared with other cars, like a vehicles.meta of several cars, stay
+					if folder != "" || owners[file] == 1 {
+						excluded[file] = key
+					}
+				}
+				if folder == "" {
+					changes.Applied = append(changes.Applied, plan.Override{Key: key, Action: EXCLUDE, Target: car.Model})
+				}
+			}
+			continue
+		}
+
+		model := ""
+		if override.Rename != "" {
+			if len(matched) != 1 {
+				changes.warn("Override renames %d cars, it needs to match exactly one, key=%s", len(matched), key)
+			} else {
+				car := matched[0]
+				model = strings.ToLower(override.Rename)
+				files := make(map[string]bool)
+				for _, file := range carFiles(car) {
+					files[file] = true
+				}
+				changes.renames = append(changes.renames, rename{from: car.Model, to: model, files: files})
+				changes.Applied = append(changes.Applied, plan.Override{Key: key, Action: RENAME, Target: car.Model, Value: model})
+			}
+		}
+
+		if override.Category != "" {
+			for _, car := range matched {
+				target := car.Model
+				if model != "" {
+					target = model
+				}
+				changes.Applied = append(changes.Applied, plan.Override{Key: key, Action: CATEGORY, Target: target, Value: override.Category})
+			}
+		}
+	}
+
+	removeFiles(result, excluded)
+	return changes
+}
+
+// Merge adds the changes of another input tree.
+func (c *Changes) Merge(other *Changes) {
+	c.Applied = append(c.Applied, other.Applied...)
+	c.Warnings = append(c.Warnings, other.Warnings...)
+	c.renames = append(c.renames, other.renames...)
+	for key := range other.matched {
+		if c.matched == nil {
+			c.matched = make(map[string]bool)
+		}
+		c.matched[key] = true
+	}
+}
+
+// Unmatched returns the keys of o that matched no folder or model of any input tree.
+func (c *Changes) Unmatched(o Overrides) []string {
+	var keys []string
+	for key := range o {
+		if !c.matched[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// RenameOperations renames the stream files of renamed cars and makes the copy of their data
+// files replace the old model name.
+func (c *Changes) RenameOperations(ops []plan.Operation) {
+	for i := range ops {
+		op := &ops[i]
+		for _, rename := range c.renames {
+			if !rename.files[op.Source] {
+				continue
+			}
+			switch op.Kind {
+			case plan.STREAM:
+				name := path.Base(op.Destination)
+				if carfinder.StreamFileModel([]string{rename.from}, name) == rename.from {
+					op.Destination = path.Join(path.Dir(op.Destination), rename.to+name[len(rename.from):])
+					op.Action = plan.RENAME
+				}
+			case plan.DATA:
+				if strings.HasSuffix(op.Destination, "_"+rename.from+".meta") {
+					op.Destination = strings.TrimSuffix(op.Destination, rename.from+".meta") + rename.to + ".meta"
+				}
+				op.Rename = &plan.Rename{From: rename.from, To: rename.to}
+				op.Action = plan.RENAME
+			}
+		}
+	}
+}
+
+// RenameCars replaces the models of renamed cars in a list of cars.
+func (c *Changes) RenameCars(cars []string) []string {
+	renamed := make([]string, 0, len(cars))
+	for _, car := range cars {
+		for _, rename := range c.renames {
+			if car == rename.from {
+				car = rename.to
+			}
+		}
+		renamed = append(renamed, car)
+	}
+	return renamed
+}
+
+func (c *Changes) warn(format string, args ...interface{}) {
+	c.Warnings = append(c.Warnings, fmt.Sprintf(format, args...))
+}
+
+// forceType sets the type of the data file at target. A meta skipped for its unknown root
+// tag becomes a data file.
+func forceType(result *scanner.Result, target string, _type dft.DataFileType) bool {
+	for i := range result.DataFiles {
+		if strings.EqualFold(result.DataFiles[i].Path, target) {
+			result.DataFiles[i].Type = _type
+			return true
+		}
+	}
+	for i, skipped := range result.Skipped {
+		if skipped.UnknownTag != "" && strings.EqualFold(skipped.Path, target) {
+			result.Skipped = append(result.Skipped[:i], result.Skipped[i+1:]...)
+			result.DataFiles = append(result.DataFiles, dft.DataFile{Path: skipped.Path, Name: filepath.Base(skipped.Path), Type: _type})
+			return true
+		}
+	}
+	return false
+}
+
+func removeFiles(result *scanner.Result, excluded map[string]string) {
+	if len(excluded) == 0 {
+		return
+	}
+	skip := func(path string) bool {
+		key, ok := excluded[path]
+		if ok {
+			result.Skipped = append(result.Skipped, dft.SkippedFile{Path: path, Reason: fmt.Sprintf("excluded by override %q", key)})
+		}
+		return ok
+	}
+
+	var streamFiles []dft.StreamFile
+	for _, file := range result.StreamFiles {
+		if !skip(file.Path) {
+			streamFiles = append(streamFiles, file)
+		}
+	}
+	var dataFiles []dft.DataFile
+	for _, file := range result.DataFiles {
+		if !skip(file.Path) {
+			dataFiles = append(dataFiles, file)
+		}
+	}
+	var audioFiles []dft.AudioFile
+	for _, file := range result.AudioFiles {
+		if !skip(file.Path) {
+			audioFiles = append(audioFiles, file)
+		}
+	}
+	result.StreamFiles, result.DataFiles, result.AudioFiles = streamFiles, dataFiles, audioFiles
+}
+
+func carFiles(car *carfinder.Car) []string {
+	var files []string
+	files = append(files, car.StreamFiles...)
+	files = append(files, car.DataFiles...)
+	return append(files, car.AudioFiles...)
+}
+
+func folderFiles(root string, folder string, result *scanner.Result) []string {
+	var files []string
+	for _, file := range result.StreamFiles {
+		files = append(files, file.Path)
+	}
+	for _, file := range result.DataFiles {
+		files = append(files, file.Path)
+	}
+	for _, file := range result.AudioFiles {
+		files = append(files, file.Path)
+	}
+
+	var inFolder []string
+	for _, file := range files {
+		if topLevelFolder(root, file) == folder {
+			inFolder = append(inFolder, file)
+		}
+	}
+	return inFolder
+}
+
+func topLevelFolders(root string, result *scanner.Result) []string {
+	var folders []string
+	add := func(path string) {
+		if folder := topLevelFolder(root, path); folder != "" && !containsFold(folders, folder) {
+			folders = append(folders, folder)
+		}
+	}
+	for _, file := range result.StreamFiles {
+		add(file.Path)
+	}
+	for _, file := range result.DataFiles {
+		add(file.Path)
+	}
+	for _, file := range result.AudioFiles {
+		add(file.Path)
+	}
+	for _, file := range result.Skipped {
+		add(file.Path)
+	}
+	return folders
+}
+
+func topLevelFolder(root string, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 2 || parts[0] == ".." {
+		return ""
+	}
+	return parts[0]
+}
+
+func findFolder(folders []string, key string) string {
+	for _, folder := range folders {
+		if strings.EqualFold(folder, key) {
+			return folder
+		}
+	}
+	return ""
+}
+
+func containsFold(items []string, item string) bool {
+	return findFolder(items, item) != ""
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,100 @@
+package overrides
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+		check   func(t *testing.T, o Overrides)
+	}{
+		{
+			name: "every action",
+			json: `{
+				"adder": {"Rename": "myadder", "Category": "super"},
+				"broken": {"Exclude": true},
+				"zentorno": {"DataTypes": {"data/zentorno_colors.meta": "CARCOLS"}}
+			}`,
+			check: func(t *testing.T, o Overrides) {
+				if len(o) != 3 {
+					t.Fatalf("loaded %d overrides, want 3", len(o))
+				}
+				if o["adder"].Rename != "myadder" || o["adder"].Category != "super" {
+					t.Errorf("adder = %+v", o["adder"])
+				}
+				if !o["broken"].Exclude {
+					t.Errorf("broken = %+v, want excluded", o["broken"])
+				}
+				if o["zentorno"].DataTypes["data/zentorno_colors.meta"] != "CARCOLS" {
+					t.Errorf("zentorno = %+v", o["zentorno"])
+				}
+			},
+		},
+		{
+			name: "data types are case insensitive",
+			json: `{"adder": {"DataTypes": {"data/colors.meta": "carcols"}}}`,
+		},
+		{
+			name: "rename with underscores",
+			json: `{"adder": {"Rename": "my_adder_2"}}`,
+			check: func(t *testing.T, o Overrides) {
+				if o["adder"].Rename != "my_adder_2" {
+					t.Errorf("adder = %+v", o["adder"])
+				}
+			},
+		},
+		{
+			name: "empty file",
+			json: `{}`,
+			check: func(t *testing.T, o Overrides) {
+				if len(o) != 0 {
+					t.Errorf("loaded %d overrides, want none", len(o))
+				}
+			},
+		},
+		{name: "unknown field", json: `{"adder": {"Rname": "myadder"}}`, wantErr: `unknown field "Rname"`},
+		{name: "wrong type", json: `{"adder": {"Exclude": "yes"}}`, wantErr: "cannot unmarshal"},
+		{name: "broken json", json: `{"adder": `, wantErr: "invalid overrides"},
+		{name: "empty override", json: `{"adder": null}`, wantErr: `override "adder" is empty`},
+		{name: "empty key", json: `{"": {"Exclude": true}}`, wantErr: `override "" is empty`},
+		{name: "rename with spaces", json: `{"adder": {"Rename": "my adder"}}`, wantErr: "only contain letters, digits and underscores"},
+		{name: "rename with path", json: `{"adder": {"Rename": "../adder"}}`, wantErr: "only contain letters, digits and underscores"},
+		{name: "exclude and rename", json: `{"adder": {"Exclude": true, "Rename": "myadder"}}`, wantErr: "both excludes and renames"},
+		{name: "unknown data type", json: `{"adder": {"DataTypes": {"data/x.meta": "WHEELS"}}}`, wantErr: `unknown data file type "WHEELS"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), FileName)
+			if err := os.WriteFile(path, []byte(test.json), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			o, err := Load(path)
+			if test.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("Load() = %v, want an error containing %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() = %v", err)
+			}
+			if test.check != nil {
+				test.check(t, o)
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	o, err := Load(filepath.Join(t.TempDir(), FileName))
+	if o != nil || err != nil {
+		t.Errorf("Load() of a missing file = %v, %v, want nil, nil", o, err)
+	}
+}
@@ -0,0 +1,194 @@
+package plan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/dft"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+)
+
+// PathFor returns where the plan of a merge into outputPath is written when no plan file is
+// given. It is kept next to the output, so it never lands in an input path and changes its fingerprint.
+func PathFor(outputPath string) string {
+	return filepath.Join(filepath.Dir(outputPath), filepath.Base(outputPath)+".plan.json")
+}
+
+type Action string
+
+const (
+	COPY   Action = "copy"
+	RENAME Action = "rename"
+)
+
+type Kind string
+
+const (
+	STREAM Kind = "stream"
+	DATA   Kind = "data"
+	AUDIO  Kind = "audio"
+)
+
+// Operation copies Source to Destination, which is relative to the output path.
+type Operation struct {
+	Action      Action
+	Kind        Kind
+	Source      string
+	Destination string
+	Type        string  `json:",omitempty"` // data file type for data operations
+	Rename      *Rename `json:",omitempty"` // model renamed inside a data file while it is copied
+}
+
+// Rename replaces the model name From with To in the values of a data file.
+type Rename struct {
+	From string
+	To   string
+}
+
+// Override is one change an overrides file made to a merge.
+type Override struct {
+	Key    string // folder or model the override is keyed by
+	Action string // exclude, rename, type or category
+	Target string // car, model or file the override changed
+	Value  string `json:",omitempty"`
+}
+
+// Conflict lists every source that would be written to the same destination.
+// Operations run in order, so the last source wins.
+type Conflict struct {
+	Destination string
+	Sources     []string
+}
+
+// Shadowed is a car or stream file of an input path that lost to the same model or file
+// name in an input path with a higher priority.
+type Shadowed struct {
+	Kind   string // model or stream
+	Name   string
+	Winner string // folder or file that is merged
+	Loser  string // folder or file that is left out
+}
+
+type Plan struct {
+	InputPath    string
+	ExtraInputs  []string `json:",omitempty"`
+	OutputPath   string
+	Clean        bool
+	Fingerprint  string
+	Operations   []Operation
+	Manifest     []string
+	Cars         []string
+	NoStreamCars []string
+	NoDataCars   []string
+	Skipped      []dft.SkippedFile
+	Conflicts    []Conflict
+	Overrides    []Override
+	Shadowed     []Shadowed
+	Warnings     []string `json:",omitempty"` // problems of the overrides file
+}
+
+// InputRoots returns every input path of the plan, highest priority first.
+func (p *Plan) InputRoots() []string {
+	if p.InputPath == "" {
+		return nil
+	}
+	return append([]string{p.InputPath}, p.ExtraInputs...)
+}
+
+func (p *Plan) OperationsOfKind(kind Kind) []Operation {
+	var ops []Operation
+	for _, op := range p.Operations {
+		if op.Kind == kind {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// FindConflicts returns the destinations that more than one operation writes to.
+func FindConflicts(ops []Operation) []Conflict {
+	sources := make(map[string][]string)
+	var order []string
+	for _, op := range ops {
+		if _, ok := sources[op.Destination]; !ok {
+			order = append(order, op.Destination)
+		}
+		sources[op.Destination] = append(sources[op.Destination], op.Source)
+	}
+
+	var conflicts []Conflict
+	for _, destination := range order {
+		if len(sources[destination]) > 1 {
+			conflicts = append(conflicts, Conflict{Destination: destination, Sources: sources[destination]})
+		}
+	}
+	return conflicts
+}
+
+// Fingerprint hashes the settings that change what a merge copies, the overrides file at
+// overridesPath and the path, size and modification time of every file below the input paths.
+func Fingerprint(_flags flags.Flags, overridesPath string) (string, error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "include %q\nexclude %q\nexcluded cars %q\n", _flags.Include, _flags.Exclude, _flags.ExcludedCars)
+
+	overridesData, err := os.ReadFile(overridesPath)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	fmt.Fprintf(hash, "overrides %d\n", len(overridesData))
+	hash.Write(overridesData)
+
+	for i, root := range _flags.InputRoots() {
+		if i > 0 {
+			fmt.Fprintf(hash, "\x00root %d\n", i)
+		}
+		if err := fingerprintRoot(hash, root); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func fingerprintRoot(hash io.Writer, root string) error {
+	return filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if f.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00%d\n", filepath.ToSlash(rel), f.Size(), f.ModTime().UnixNano())
+		return nil
+	})
+}
+
+func Load(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Plan{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %w", path, err)
+	}
+	return p, nil
+}
+
+func Save(p *Plan, path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
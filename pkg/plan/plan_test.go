@@ -0,0 +1,110 @@
+package plan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+)
+
+func TestFingerprint(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(t *testing.T, _flags *flags.Flags, input string, overridesPath string)
+		same   bool
+	}{
+		{
+			name:   "nothing changed",
+			change: func(t *testing.T, _flags *flags.Flags, input string, overridesPath string) {},
+			same:   true,
+		},
+		{
+			name: "verbose and concurrency",
+			change: func(t *testing.T, _flags *flags.Flags, input string, overridesPath string) {
+				_flags.Verbose = true
+				_flags.Concurrency = 8
+			},
+			same: true,
+		},
+		{
+			name: "input file",
+			change: func(t *testing.T, _flags *flags.Flags, input string, overridesPath string) {
+				writeFile(t, filepath.Join(input, "adder", "stream", "adder.yft"), "new adder model")
+			},
+		},
+		{
+			name: "overrides file",
+			change: func(t *testing.T, _flags *flags.Flags, input string, overridesPath string) {
+				writeFile(t, overridesPath, `{"adder": {"Rename": "myadder"}}`)
+			},
+		},
+		{
+			name: "overrides file removed",
+			change: func(t *testing.T, _flags *flags.Flags, input string, overridesPath string) {
+				if err := os.Remove(overridesPath); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "include",
+			change: func(t *testing.T, _flags *flags.Flags, input string, overridesPath string) {
+				_flags.Include = []string{"adder/**"}
+			},
+		},
+		{
+			name: "exclude",
+			change: func(t *testing.T, _flags *flags.Flags, input string, overridesPath string) {
+				_flags.Exclude = []string{"*.txt"}
+			},
+		},
+		{
+			name: "excluded cars",
+			change: func(t *testing.T, _flags *flags.Flags, input string, overridesPath string) {
+				_flags.ExcludedCars = []string{"adder"}
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "cars")
+			writeFile(t, filepath.Join(input, "adder", "stream", "adder.yft"), "adder model")
+			overridesPath := filepath.Join(dir, "overrides.json")
+			writeFile(t, overridesPath, `{"adder": {"Category": "super"}}`)
+			_flags := flags.Flags{InputPath: input, OutputPath: filepath.Join(dir, "merged")}
+
+			before, err := Fingerprint(_flags, overridesPath)
+			if err != nil {
+				t.Fatalf("Fingerprint() = %v", err)
+			}
+			test.change(t, &_flags, input, overridesPath)
+			after, err := Fingerprint(_flags, overridesPath)
+			if err != nil {
+				t.Fatalf("Fingerprint() after the change = %v", err)
+			}
+			if same := before == after; same != test.same {
+				t.Errorf("fingerprints equal = %v, want %v", same, test.same)
+			}
+		})
+	}
+}
+
+func TestPathFor(t *testing.T) {
+	got := PathFor(filepath.Join("servers", "merged-cars"))
+	want := filepath.Join("servers", "merged-cars.plan.json")
+	if got != want {
+		t.Errorf("PathFor() = %s, want %s", got, want)
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,41 @@
+package progressbar
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/x/term"
+)
+
+// Bar draws a single progress line on a terminal. It draws nothing when the output is
+// redirected, so logs and pipes stay clean.
+type Bar struct {
+	out     *os.File
+	title   string
+	model   progress.Model
+	enabled bool
+}
+
+func New(out *os.File, title string) *Bar {
+	return &Bar{
+		out:     out,
+		title:   title,
+		model:   progress.New(progress.WithSolidFill("#ff7df9"), progress.WithWidth(40)),
+		enabled: term.IsTerminal(out.Fd()),
+	}
+}
+
+func (b *Bar) Update(done int, total int) {
+	if !b.enabled || total == 0 {
+		return
+	}
+	fmt.Fprintf(b.out, "\r%s %s %d/%d", b.title, b.model.ViewAs(float64(done)/float64(total)), done, total)
+}
+
+// Finish ends the progress line so the next output starts on its own line.
+func (b *Bar) Finish() {
+	if b.enabled {
+		fmt.Fprintln(b.out)
+	}
+}
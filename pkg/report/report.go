@@ -0,0 +1,149 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/dft"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/overrides"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/plan"
+	"github.com/charmbracelet/log"
+)
+
+// Report is the machine readable summary of a merge, written next to the output directory.
+type Report struct {
+	InputPath    string
+	ExtraInputs  []string `json:",omitempty"`
+	OutputPath   string
+	StartedAt    time.Time
+	Milliseconds int64
+	Success      bool
+	Error        string `json:",omitempty"`
+	Files        []File
+	Cars         []string
+	NoStreamCars []string
+	NoDataCars   []string
+	Skipped      []dft.SkippedFile
+	Conflicts    []plan.Conflict
+	Overrides    []plan.Override
+	Shadowed     []plan.Shadowed
+	Deleted      []string          `json:",omitempty"` // outputs of the last merge this merge removed
+	Categories   map[string]string `json:",omitempty"` // model -> category set by an override
+	Warnings     []string
+	Timings      []Timing
+
+	logger *log.Logger
+}
+
+type File struct {
+	Source      string
+	Destination string
+	Action      plan.Action
+	Kind        plan.Kind
+	Unchanged   bool `json:",omitempty"` // kept from the last merge instead of copied again
+}
+
+type Timing struct {
+	Stage        string
+	Milliseconds int64
+}
+
+func New(inputPath string, outputPath string) *Report {
+	return &Report{
+		InputPath:  inputPath,
+		OutputPath: outputPath,
+		StartedAt:  time.Now(),
+		Files:      make([]File, 0),
+		Warnings:   make([]string, 0),
+	}
+}
+
+// PathFor returns where the report of a merge into outputPath is written.
+func PathFor(outputPath string) string {
+	return filepath.Join(filepath.Dir(outputPath), filepath.Base(outputPath)+".report.json")
+}
+
+// SetLogger makes Warn log to logger instead of the default logger.
+func (r *Report) SetLogger(logger *log.Logger) {
+	r.logger = logger
+}
+
+// Warn logs a warning and keeps it in the report.
+func (r *Report) Warn(msg string, keyvals ...interface{}) {
+	logger := r.logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Helper()
+	logger.Warn(msg, keyvals...)
+
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", keyvals[i], keyvals[i+1])
+	}
+	r.Warnings = append(r.Warnings, b.String())
+}
+
+// Time runs stage and records how long it took.
+func (r *Report) Time(stage string, run func() error) error {
+	start := time.Now()
+	err := run()
+	r.Timings = append(r.Timings, Timing{Stage: stage, Milliseconds: time.Since(start).Milliseconds()})
+	return err
+}
+
+// AddPlan records the files, skipped files, conflicts, applied overrides and shadowed copies of a plan.
+func (r *Report) AddPlan(p *plan.Plan) {
+	for _, op := range p.Operations {
+		r.Files = append(r.Files, File{Source: op.Source, Destination: op.Destination, Action: op.Action, Kind: op.Kind})
+	}
+	r.Skipped = append(r.Skipped, p.Skipped...)
+	r.Conflicts = append(r.Conflicts, p.Conflicts...)
+	r.ExtraInputs = p.ExtraInputs
+	r.Shadowed = append(r.Shadowed, p.Shadowed...)
+	r.Overrides = append(r.Overrides, p.Overrides...)
+	for _, override := range p.Overrides {
+		if override.Action == overrides.CATEGORY {
+			if r.Categories == nil {
+				r.Categories = make(map[string]string)
+			}
+			r.Categories[override.Target] = override.Value
+		}
+	}
+}
+
+// Finish stamps the total duration and the outcome of the merge.
+func (r *Report) Finish(err error) {
+	r.Milliseconds = time.Since(r.StartedAt).Milliseconds()
+	r.Success = err == nil
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+func Load(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Report{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+	return r, nil
+}
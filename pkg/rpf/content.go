@@ -0,0 +1,99 @@
+package rpf
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/manifestgen"
+)
+
+// ContentDataFile is a data file a single player DLC loads through its content.xml.
+type ContentDataFile struct {
+	Path     string // slash separated, relative to the archive root
+	FileType string
+}
+
+type contentXML struct {
+	XMLName          xml.Name           `xml:"CDataFileMgr__ContentsOfDataFileXml"`
+	DisabledFiles    struct{}           `xml:"disabledFiles"`
+	IncludedXMLFiles struct{}           `xml:"includedXmlFiles"`
+	IncludedData     struct{}           `xml:"includedDataFiles"`
+	DataFiles        []contentDataItem  `xml:"dataFiles>Item"`
+	ChangeSets       []contentChangeSet `xml:"contentChangeSets>Item"`
+	PatchFiles       struct{}           `xml:"patchFiles"`
+}
+
+type contentDataItem struct {
+	Filename   string       `xml:"filename"`
+	FileType   string       `xml:"fileType"`
+	Overlay    contentValue `xml:"overlay"`
+	Disabled   contentValue `xml:"disabled"`
+	Persistent contentValue `xml:"persistent"`
+}
+
+type contentValue struct {
+	Value bool `xml:"value,attr"`
+}
+
+type contentChangeSet struct {
+	ChangeSetName     string   `xml:"changeSetName"`
+	MapChangeSetData  struct{} `xml:"mapChangeSetData"`
+	FilesToInvalidate struct{} `xml:"filesToInvalidate"`
+	FilesToDisable    struct{} `xml:"filesToDisable"`
+	FilesToEnable     []string `xml:"filesToEnable>Item"`
+}
+
+// ContentDataFiles resolves the data_file entries of the fxmanifest.lua of a resource to
+// the files they match.
+func ContentDataFiles(resourcePath string) ([]ContentDataFile, error) {
+	manifest, err := manifestgen.ParseManifest(filepath.Join(resourcePath, "fxmanifest.lua"))
+	if err != nil {
+		return nil, err
+	}
+
+	var dataFiles []ContentDataFile
+	for _, entry := range manifest.DataFiles {
+		matches, err := filepath.Glob(filepath.Join(resourcePath, filepath.FromSlash(entry.Path)))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			rel, err := filepath.Rel(resourcePath, match)
+			if err != nil {
+				return nil, err
+			}
+			rel = filepath.ToSlash(rel)
+			if info, err := os.Stat(match); err == nil && info.IsDir() {
+				rel += "/"
+			}
+			dataFiles = append(dataFiles, ContentDataFile{Path: rel, FileType: entry.Type})
+		}
+	}
+	return dataFiles, nil
+}
+
+// ContentXML renders the content.xml of a DLC named dlcName that enables all dataFiles.
+func ContentXML(dlcName string, dataFiles []ContentDataFile) ([]byte, error) {
+	content := contentXML{}
+	changeSet := contentChangeSet{ChangeSetName: strings.ToUpper(dlcName) + "_AUTOGEN"}
+	for _, dataFile := range dataFiles {
+		filename := "dlc_" + dlcName + ":/" + dataFile.Path
+		content.DataFiles = append(content.DataFiles, contentDataItem{
+			Filename: filename,
+			FileType: dataFile.FileType,
+			Disabled: contentValue{Value: true},
+		})
+		changeSet.FilesToEnable = append(changeSet.FilesToEnable, filename)
+	}
+	content.ChangeSets = append(content.ChangeSets, changeSet)
+
+	data, err := xml.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
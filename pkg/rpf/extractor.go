@@ -3,22 +3,42 @@ package rpf
 import (
 	"bytes"
 	"compress/zlib"
-	"encoding/binary"
 	"fmt"
 	"io"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/hash"
 	"github.com/charmbracelet/log"
 )
 
 type Extractor struct {
-	CachePath string
-	Header    *RPFHeader
-	Entries   []interface{} // Can be DirectoryEntry or FileEntry
-	Names     map[int32]string
+	CachePath  string
+	Extensions []string // only extract files with these extensions, all when empty
+	OnProgress func(done int, total int)
+	Header     *RPFHeader
+	Entries    []interface{} // Can be DirectoryEntry or FileEntry
+	Names      map[int32]string
+	Extracted  []string
+	Skipped    []SkippedEntry
+	Dictionary *hash.Dictionary // when set, every name of the archive is added to it
 }
 
+// SkippedEntry is a file of the archive that was not extracted.
+type SkippedEntry struct {
+	Name         string
+	ResourceType byte
+	Unknown      bool // resourceTypeExtensions has no extension for the type
+	Reason       string
+}
+
+// RawFile is the resource type of files that are stored as they are. Their name in the
+// archive keeps its extension.
+const RawFile byte = 0x00
+
 var resourceTypeExtensions = map[byte]string{
 	0x01: ".ytd",    // Texture Dictionary
 	0x02: ".yft",    // Fragment
@@ -74,78 +94,74 @@ func (e *Extractor) Extract(rpfPath string) error {
 		return err
 	}
 
-	// Read TOC entries
-	tocSize := e.Header.EntryCount * 16
-	if tocSize <= 0 || tocSize > 1<<30 { // Max 1GB for safety
-		log.Error("Invalid TOC size calculated", "size", tocSize)
-		return fmt.Errorf("invalid TOC size calculated: %d", tocSize)
-	}
-
-	toc := make([]byte, tocSize)
-	if _, err := file.Seek(TOCOffset, 0); err != nil {
-		return err
-	}
-	if _, err := io.ReadFull(file, toc); err != nil {
-		return err
-	}
-
-	// Read string table
-	stringTableSize := e.Header.TOCSize - tocSize
-	stringTable := make([]byte, stringTableSize)
-	if _, err := io.ReadFull(file, stringTable); err != nil {
+	e.Entries, e.Names, err = readTOC(file, e.Header)
+	if err != nil {
 		return err
 	}
-
-	// Parse string table
-	e.Names = make(map[int32]string)
-	currentOffset := int32(0)
-	currentString := ""
-	for i := 0; i < len(stringTable); i++ {
-		if stringTable[i] == 0 {
-			if currentString != "" {
-				e.Names[currentOffset] = currentString
-				log.Debug("Found filename", "offset", currentOffset, "name", currentString)
-			}
-			currentOffset = int32(i + 1)
-			currentString = ""
-		} else {
-			currentString += string(stringTable[i])
+	e.Extracted = nil
+	e.Skipped = nil
+	if e.Dictionary != nil {
+		for _, name := range e.Names {
+			e.Dictionary.Add(hash.RPF, name, "")
 		}
 	}
 
-	// Parse entries
-	for i := 0; i < int(e.Header.EntryCount); i++ {
-		offset := i * 16
-		nameOffset := binary.LittleEndian.Uint32(toc[offset:])
-
-		if (nameOffset & 0x80000000) != 0 {
-			dir := &DirectoryEntry{}
-			binary.Read(bytes.NewReader(toc[offset:offset+16]), binary.LittleEndian, dir)
-			e.Entries = append(e.Entries, dir)
-		} else {
-			file := &FileEntry{}
-			binary.Read(bytes.NewReader(toc[offset:offset+16]), binary.LittleEndian, file)
-			e.Entries = append(e.Entries, file)
-		}
+	// Files keep the folders they have in the archive
+	tree, err := BuildTree(e.Entries, e.Names)
+	if err != nil {
+		log.Debug("Failed to build the directory tree, extracting files flat", "err", err)
 	}
+	paths := make(map[*FileEntry]string)
+	tree.Walk(func(node *Node) {
+		if node.Entry != nil {
+			paths[node.Entry] = node.Path
+		}
+	})
 
 	// Extract files
+	var fileEntries []*FileEntry
 	for _, entry := range e.Entries {
 		if fileEntry, ok := entry.(*FileEntry); ok {
-			if err := e.extractFile(file, fileEntry); err != nil {
-				return err
-			}
+			fileEntries = append(fileEntries, fileEntry)
+		}
+	}
+	for i, fileEntry := range fileEntries {
+		name, ok := paths[fileEntry]
+		if !ok {
+			name = e.Names[fileEntry.NameOffset]
+		}
+		if err := e.extractFile(file, fileEntry, name); err != nil {
+			return err
+		}
+		if e.OnProgress != nil {
+			e.OnProgress(i+1, len(fileEntries))
 		}
 	}
 
 	return nil
 }
 
-func (e *Extractor) extractFile(rpf *os.File, entry *FileEntry) error {
-	// Get appropriate extension for the file
+func (e *Extractor) extractFile(rpf *os.File, entry *FileEntry, name string) error {
+	// Get appropriate extension for the file, raw files keep the one in their name
 	extension := resourceTypeExtensions[entry.ResourceType]
-	if extension == "" {
-		// Skip unknown file types
+	if entry.ResourceType == RawFile {
+		extension = ""
+	} else if extension == "" {
+		log.Debug("Skipping file with unknown resource type", "name", name, "type", entry.ResourceType)
+		e.Skipped = append(e.Skipped, SkippedEntry{
+			Name:         name,
+			ResourceType: entry.ResourceType,
+			Unknown:      true,
+			Reason:       fmt.Sprintf("unknown resource type 0x%02X", entry.ResourceType),
+		})
+		return nil
+	}
+	if fileExtension := path.Ext(name + extension); !e.wantsExtension(fileExtension) {
+		e.Skipped = append(e.Skipped, SkippedEntry{
+			Name:         name,
+			ResourceType: entry.ResourceType,
+			Reason:       "extension " + fileExtension + " not selected",
+		})
 		return nil
 	}
 
@@ -194,7 +210,11 @@ func (e *Extractor) extractFile(rpf *os.File, entry *FileEntry) error {
 	}
 
 	// Preserve directory structure
-	outPath := filepath.Join(e.CachePath, filepath.Dir(e.Names[entry.NameOffset]), filepath.Base(e.Names[entry.NameOffset])+extension)
+	outPath := filepath.Join(e.CachePath, filepath.FromSlash(name)+extension)
+	if rel, err := filepath.Rel(e.CachePath, outPath); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		e.Skipped = append(e.Skipped, SkippedEntry{Name: name, ResourceType: entry.ResourceType, Reason: "path leaves the output directory"})
+		return nil
+	}
 	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
 		return err
 	}
@@ -220,7 +240,26 @@ func (e *Extractor) extractFile(rpf *os.File, entry *FileEntry) error {
 		return nestedExtractor.Extract(outPath)
 	}
 
-	return os.WriteFile(outPath, data, 0644)
+	if err := os.WriteFile(outPath, data, 0644); err != nil {
+		return err
+	}
+	e.Extracted = append(e.Extracted, outPath)
+	return nil
+}
+
+func (e *Extractor) wantsExtension(extension string) bool {
+	if len(e.Extensions) == 0 {
+		return true
+	}
+	for _, wanted := range e.Extensions {
+		if !strings.HasPrefix(wanted, ".") {
+			wanted = "." + wanted
+		}
+		if strings.EqualFold(wanted, extension) {
+			return true
+		}
+	}
+	return false
 }
 
 func (e *Extractor) decompressData(compressedData []byte) ([]byte, error) {
@@ -237,3 +276,33 @@ func (e *Extractor) decompressData(compressedData []byte) ([]byte, error) {
 func (e *Extractor) IsCompressed(flags uint32) bool {
 	return (flags & 1) != 0
 }
+
+// LogSummary logs how many files were extracted and, per resource type, how many were
+// skipped because resourceTypeExtensions has no extension for it.
+func (e *Extractor) LogSummary() {
+	log.Info("Extracted RPF", "files", len(e.Extracted), "output_folder", e.CachePath)
+
+	unknownTypes := make(map[byte][]string)
+	filtered := 0
+	for _, skipped := range e.Skipped {
+		if skipped.Unknown {
+			unknownTypes[skipped.ResourceType] = append(unknownTypes[skipped.ResourceType], skipped.Name)
+		} else {
+			filtered++
+		}
+	}
+
+	if filtered > 0 {
+		log.Info("Skipped files with other extensions", "files", filtered)
+	}
+	types := make([]int, 0, len(unknownTypes))
+	for resourceType := range unknownTypes {
+		types = append(types, int(resourceType))
+	}
+	sort.Ints(types)
+	for _, resourceType := range types {
+		names := unknownTypes[byte(resourceType)]
+		log.Warn("Skipped files with unknown resource type", "type", fmt.Sprintf("0x%02X", resourceType), "files", len(names))
+		log.Debug("Skipped files", "type", fmt.Sprintf("0x%02X", resourceType), "names", names)
+	}
+}
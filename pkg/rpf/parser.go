@@ -1,8 +1,10 @@
 package rpf
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/charmbracelet/log"
@@ -48,3 +50,60 @@ func ParseRPF(filepath string) (*RPFHeader, error) {
 
 	return header, nil
 }
+
+// readTOC reads the entries and the names table that follow the header of an archive.
+// Names are keyed by their offset in the names table.
+func readTOC(file *os.File, header *RPFHeader) ([]interface{}, map[int32]string, error) {
+	tocSize := header.EntryCount * 16
+	if tocSize <= 0 || tocSize > 1<<30 { // Max 1GB for safety
+		log.Error("Invalid TOC size calculated", "size", tocSize)
+		return nil, nil, fmt.Errorf("invalid TOC size calculated: %d", tocSize)
+	}
+	if header.TOCSize < tocSize {
+		return nil, nil, fmt.Errorf("TOC size %d is smaller than its %d entries", header.TOCSize, header.EntryCount)
+	}
+
+	toc := make([]byte, tocSize)
+	if _, err := file.Seek(TOCOffset, 0); err != nil {
+		return nil, nil, err
+	}
+	if _, err := io.ReadFull(file, toc); err != nil {
+		return nil, nil, err
+	}
+
+	// Read string table
+	stringTable := make([]byte, header.TOCSize-tocSize)
+	if _, err := io.ReadFull(file, stringTable); err != nil {
+		return nil, nil, err
+	}
+
+	names := make(map[int32]string)
+	currentOffset := int32(0)
+	for i := 0; i < len(stringTable); i++ {
+		if stringTable[i] == 0 {
+			if i > int(currentOffset) {
+				names[currentOffset] = string(stringTable[currentOffset:i])
+				log.Debug("Found filename", "offset", currentOffset, "name", names[currentOffset])
+			}
+			currentOffset = int32(i + 1)
+		}
+	}
+
+	var entries []interface{}
+	for i := 0; i < int(header.EntryCount); i++ {
+		offset := i * 16
+		nameOffset := binary.LittleEndian.Uint32(toc[offset:])
+
+		if (nameOffset & 0x80000000) != 0 {
+			dir := &DirectoryEntry{}
+			binary.Read(bytes.NewReader(toc[offset:offset+16]), binary.LittleEndian, dir)
+			entries = append(entries, dir)
+		} else {
+			file := &FileEntry{}
+			binary.Read(bytes.NewReader(toc[offset:offset+16]), binary.LittleEndian, file)
+			entries = append(entries, file)
+		}
+	}
+
+	return entries, names, nil
+}
@@ -0,0 +1,169 @@
+package rpf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+)
+
+// Node is a directory or a file of an archive.
+type Node struct {
+	Name         string
+	Path         string // slash separated, relative to the archive root
+	IsDir        bool
+	Size         uint32  `json:",omitempty"` // size inside the archive, compressed if Compressed is set
+	Compressed   bool    `json:",omitempty"`
+	ResourceType byte    `json:",omitempty"`
+	Extension    string  `json:",omitempty"` // extension the Extractor gives the file, empty for unknown types
+	Children     []*Node `json:",omitempty"`
+
+	Entry *FileEntry `json:"-"`
+}
+
+// ReadTree reads the table of contents of an archive and returns its root directory
+// without extracting anything.
+func ReadTree(rpfPath string) (*Node, error) {
+	header, err := ParseRPF(rpfPath)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(rpfPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, names, err := readTOC(file, header)
+	if err != nil {
+		return nil, err
+	}
+	return BuildTree(entries, names)
+}
+
+// BuildTree links the entries of an archive into a tree. The first entry is the root
+// directory and every directory owns the ContentEntryCount entries starting at its
+// ContentEntryIndex.
+func BuildTree(entries []interface{}, names map[int32]string) (*Node, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("archive has no entries")
+	}
+	rootEntry, ok := entries[0].(*DirectoryEntry)
+	if !ok {
+		return nil, fmt.Errorf("first entry of the archive is not a directory")
+	}
+
+	root := &Node{IsDir: true}
+	visited := map[int]bool{0: true}
+	if err := addChildren(root, rootEntry, entries, names, visited); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+func addChildren(parent *Node, dir *DirectoryEntry, entries []interface{}, names map[int32]string, visited map[int]bool) error {
+	first := int(dir.ContentEntryIndex)
+	last := first + int(dir.ContentEntryCount)
+	if first < 0 || last > len(entries) || last < first {
+		return fmt.Errorf("directory %q points at entries %d to %d of %d", parent.Path, first, last, len(entries))
+	}
+
+	for i := first; i < last; i++ {
+		if visited[i] {
+			return fmt.Errorf("entry %d is listed twice", i)
+		}
+		visited[i] = true
+
+		switch entry := entries[i].(type) {
+		case *DirectoryEntry:
+			name, err := entryName(names, entry.NameOffset&0x7FFFFFFF, i)
+			if err != nil {
+				return err
+			}
+			node := &Node{Name: name, Path: path.Join(parent.Path, name), IsDir: true}
+			parent.Children = append(parent.Children, node)
+			if err := addChildren(node, entry, entries, names, visited); err != nil {
+				return err
+			}
+		case *FileEntry:
+			name, err := entryName(names, entry.NameOffset, i)
+			if err != nil {
+				return err
+			}
+			parent.Children = append(parent.Children, &Node{
+				Name:         name,
+				Path:         path.Join(parent.Path, name),
+				Size:         entry.Size,
+				Compressed:   entry.Flags&1 != 0,
+				ResourceType: entry.ResourceType,
+				Extension:    resourceTypeExtensions[entry.ResourceType],
+				Entry:        entry,
+			})
+		}
+	}
+	return nil
+}
+
+// entryName looks up the name of an entry and refuses names that would leave their directory.
+func entryName(names map[int32]string, offset int32, index int) (string, error) {
+	name, ok := names[offset]
+	if !ok {
+		return "", fmt.Errorf("entry %d has no name at offset %d", index, offset)
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("entry %d has an invalid name %q", index, name)
+	}
+	return name, nil
+}
+
+// Walk calls fn for the node and everything below it, parents before their children.
+func (n *Node) Walk(fn func(node *Node)) {
+	if n == nil {
+		return
+	}
+	fn(n)
+	for _, child := range n.Children {
+		child.Walk(fn)
+	}
+}
+
+func (n *Node) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(n)
+}
+
+func (n *Node) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PATH\tSIZE\tCOMPRESSED\tTYPE")
+	files, dirs, total := 0, 0, uint64(0)
+	n.Walk(func(node *Node) {
+		if node == n {
+			return
+		}
+		if node.IsDir {
+			dirs++
+			fmt.Fprintf(tw, "%s/\t-\t-\t-\n", node.Path)
+			return
+		}
+		files++
+		total += uint64(node.Size)
+		extension := node.Extension
+		if node.ResourceType == RawFile {
+			extension = "raw"
+		} else if extension == "" {
+			extension = "unknown"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%t\t0x%02X (%s)\n", node.Path, node.Size, node.Compressed, node.ResourceType, extension)
+	})
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\n%d files in %d directories, %d bytes\n", files, dirs, total)
+	return nil
+}
@@ -0,0 +1,300 @@
+package rpf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+// Version is the version the Writer stores in the header. Archives are written in the RPF2
+// layout the Extractor reads, which GTA V and OpenIV, expecting RPF7, can't load.
+const Version = 0x52504632
+
+// MaxOffset is the highest data offset a file entry can hold in its 24 bits.
+const MaxOffset = 1<<24 - 1
+
+// Writer packs files into an archive the Extractor can read back.
+type Writer struct {
+	Compress bool // store files zlib compressed when that makes them smaller
+
+	files []writerFile
+}
+
+type writerFile struct {
+	path   string // slash separated, relative to the archive root
+	source string
+	data   []byte
+}
+
+type writerNode struct {
+	name         string
+	resourceType byte
+	file         *writerFile
+	children     []*writerNode
+	index        int
+}
+
+// AddDirectory adds every file below root, keeping their paths relative to root.
+func (w *Writer) AddDirectory(root string) error {
+	return filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if f.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		w.files = append(w.files, writerFile{path: filepath.ToSlash(rel), source: path})
+		return nil
+	})
+}
+
+// AddFile adds a file with the given content, replacing a file added before at the same path.
+func (w *Writer) AddFile(name string, data []byte) {
+	name = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/")
+	for i := range w.files {
+		if w.files[i].path == name {
+			w.files[i] = writerFile{path: name, data: data}
+			return
+		}
+	}
+	w.files = append(w.files, writerFile{path: name, data: data})
+}
+
+// RemoveFile drops the file added at name, if any.
+func (w *Writer) RemoveFile(name string) {
+	name = strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/")
+	for i := range w.files {
+		if w.files[i].path == name {
+			w.files = append(w.files[:i], w.files[i+1:]...)
+			return
+		}
+	}
+}
+
+// Write builds the archive at rpfPath. Files whose extension resourceTypeExtensions knows are
+// stored without it and with their resource type, every other file is stored as a RawFile.
+// File data is written to the archive one file at a time, and the archive is removed again
+// when packing fails.
+func (w *Writer) Write(rpfPath string) (err error) {
+	log.Debug("Starting RPF packing", "file", rpfPath, "files", len(w.files))
+
+	root, err := w.buildTree()
+	if err != nil {
+		return err
+	}
+
+	// Every directory owns a contiguous run of entries, so entries are numbered breadth first
+	entries := []*writerNode{root}
+	for i := 0; i < len(entries); i++ {
+		for _, child := range entries[i].children {
+			child.index = len(entries)
+			entries = append(entries, child)
+		}
+	}
+
+	names := []byte{0}
+	nameOffsets := map[string]int32{"": 0}
+	for _, node := range entries {
+		if _, ok := nameOffsets[node.name]; !ok {
+			nameOffsets[node.name] = int32(len(names))
+			names = append(names, node.name...)
+			names = append(names, 0)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(rpfPath), 0755); err != nil {
+		return err
+	}
+	archive, err := os.Create(rpfPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := archive.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(rpfPath)
+		}
+	}()
+
+	// Data follows the table of contents, which is written last once every offset is known
+	tocSize := len(entries)*16 + len(names)
+	offset := align(TOCOffset+tocSize, 512)
+	if _, err := archive.Seek(int64(offset), io.SeekStart); err != nil {
+		return err
+	}
+
+	toc := &bytes.Buffer{}
+	for _, node := range entries {
+		nameOffset := nameOffsets[node.name]
+		if node.file == nil {
+			contentIndex := uint32(0)
+			if len(node.children) > 0 {
+				contentIndex = uint32(node.children[0].index)
+			}
+			binary.Write(toc, binary.LittleEndian, DirectoryEntry{
+				NameOffset:        int32(uint32(nameOffset) | 0x80000000),
+				ContentEntryIndex: contentIndex,
+				ContentEntryCount: uint32(len(node.children)),
+			})
+			continue
+		}
+
+		if offset > MaxOffset {
+			return fmt.Errorf("archive is too large: %s would start at byte %d, but file offsets only go up to 16 MB", node.file.path, offset)
+		}
+		size, compressed, err := w.writeContent(archive, node.file)
+		if err != nil {
+			return err
+		}
+
+		var flags uint32
+		if compressed {
+			flags = 1
+		}
+		binary.Write(toc, binary.LittleEndian, FileEntry{
+			NameOffset:   nameOffset,
+			Size:         uint32(size),
+			Offset:       [3]byte{byte(offset >> 16), byte(offset >> 8), byte(offset)},
+			ResourceType: node.resourceType,
+			Flags:        flags,
+		})
+		offset += size
+	}
+	toc.Write(names)
+
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := binary.Write(archive, binary.LittleEndian, RPFHeader{
+		Version:    Version,
+		TOCSize:    int32(tocSize),
+		EntryCount: int32(len(entries)),
+	}); err != nil {
+		return err
+	}
+	if _, err := archive.Seek(TOCOffset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = archive.Write(toc.Bytes())
+	return err
+}
+
+func (w *Writer) buildTree() (*writerNode, error) {
+	files := make([]*writerFile, len(w.files))
+	for i := range w.files {
+		files[i] = &w.files[i]
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+
+	root := &writerNode{}
+	dirs := map[string]*writerNode{"": root}
+	stored := make(map[string]string)
+	for _, file := range files {
+		parent := root
+		dir, base := path.Split(file.path)
+		dir = strings.TrimSuffix(dir, "/")
+		if dir != "" {
+			current := ""
+			for _, part := range strings.Split(dir, "/") {
+				current = path.Join(current, part)
+				node, ok := dirs[current]
+				if !ok {
+					node = &writerNode{name: part}
+					dirs[current] = node
+					parent.children = append(parent.children, node)
+				}
+				parent = node
+			}
+		}
+
+		name, resourceType := storedName(base)
+		key := path.Join(dir, name) + fmt.Sprintf("#%d", resourceType)
+		if other, ok := stored[key]; ok {
+			return nil, fmt.Errorf("%s and %s would have the same entry in the archive", other, file.path)
+		}
+		stored[key] = file.path
+		parent.children = append(parent.children, &writerNode{name: name, resourceType: resourceType, file: file})
+	}
+	return root, nil
+}
+
+// storedName returns the name and resource type a file is stored with. Files with related
+// extensions are stored raw, since the Extractor would write the related files next to them.
+func storedName(name string) (string, byte) {
+	extension := strings.ToLower(path.Ext(name))
+	if extension == "" || len(getRelatedExtensions(extension)) > 0 {
+		return name, RawFile
+	}
+	types := make([]int, 0, len(resourceTypeExtensions))
+	for resourceType := range resourceTypeExtensions {
+		types = append(types, int(resourceType))
+	}
+	sort.Ints(types)
+	for _, resourceType := range types {
+		if resourceTypeExtensions[byte(resourceType)] == extension && len(name) > len(extension) {
+			return strings.TrimSuffix(name, path.Ext(name)), byte(resourceType)
+		}
+	}
+	return name, RawFile
+}
+
+// writeContent writes the data of file to the archive and returns how many bytes it took.
+// Only files that get compressed are read into memory, to compare both sizes.
+func (w *Writer) writeContent(archive io.Writer, file *writerFile) (int, bool, error) {
+	if file.source == "" {
+		return w.writeData(archive, file.data)
+	}
+	if w.Compress {
+		data, err := os.ReadFile(file.source)
+		if err != nil {
+			return 0, false, err
+		}
+		return w.writeData(archive, data)
+	}
+
+	source, err := os.Open(file.source)
+	if err != nil {
+		return 0, false, err
+	}
+	defer source.Close()
+	size, err := io.Copy(archive, source)
+	return int(size), false, err
+}
+
+func (w *Writer) writeData(archive io.Writer, data []byte) (int, bool, error) {
+	if w.Compress && len(data) > 0 {
+		compressed := &bytes.Buffer{}
+		zlibWriter := zlib.NewWriter(compressed)
+		if _, err := zlibWriter.Write(data); err != nil {
+			return 0, false, err
+		}
+		if err := zlibWriter.Close(); err != nil {
+			return 0, false, err
+		}
+		if compressed.Len() < len(data) {
+			size, err := archive.Write(compressed.Bytes())
+			return size, true, err
+		}
+	}
+	size, err := archive.Write(data)
+	return size, false, err
+}
+
+func align(offset int, alignment int) int {
+	return (offset + alignment - 1) / alignment * alignment
+}
@@ -0,0 +1,172 @@
+package rpf
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// randomData returns size bytes that don't compress.
+func randomData(seed int64, size int) []byte {
+	data := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+func TestWriterExtractorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		compress bool
+		files    map[string][]byte // by slash separated path in the archive
+	}{
+		{
+			name: "raw files",
+			files: map[string][]byte{
+				"fxmanifest.lua":  []byte("fx_version 'cerulean'\n"),
+				"readme.txt":      []byte("merged cars"),
+				"empty/empty.txt": {},
+			},
+		},
+		{
+			name: "nested resources",
+			files: map[string][]byte{
+				"stream/adder.yft":                  []byte("adder model"),
+				"stream/adder.ytd":                  []byte("adder textures"),
+				"data/vehicles/vehicles_adder.meta": []byte("<CVehicleModelInfo__InitDataList />"),
+			},
+		},
+		{
+			name:     "compressed",
+			compress: true,
+			files: map[string][]byte{
+				"stream/adder.ytd": bytes.Repeat([]byte("adder textures "), 1000),
+				"stream/noise.yft": randomData(1, 4096),
+			},
+		},
+		{
+			// The last files start past 8 MB, in the high byte of their 24-bit offset
+			name: "large archive",
+			files: map[string][]byte{
+				"fxmanifest.lua":   []byte("fx_version 'cerulean'\n"),
+				"stream/adder.yft": randomData(2, 8<<20),
+				"stream/t20.yft":   randomData(3, 7<<20),
+				"stream/t20.ytd":   []byte("t20 textures"),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rpfPath := filepath.Join(t.TempDir(), "cars.rpf")
+			w := &Writer{Compress: test.compress}
+			for name, data := range test.files {
+				w.AddFile(name, data)
+			}
+			if err := w.Write(rpfPath); err != nil {
+				t.Fatalf("Write() = %v", err)
+			}
+
+			e := &Extractor{CachePath: filepath.Join(t.TempDir(), "cars")}
+			if err := e.Extract(rpfPath); err != nil {
+				t.Fatalf("Extract() = %v", err)
+			}
+			if len(e.Skipped) != 0 {
+				t.Errorf("Skipped = %+v, want none", e.Skipped)
+			}
+			if len(e.Extracted) != len(test.files) {
+				t.Errorf("extracted %d files, want %d", len(e.Extracted), len(test.files))
+			}
+			for name, want := range test.files {
+				got, err := os.ReadFile(filepath.Join(e.CachePath, filepath.FromSlash(name)))
+				if err != nil {
+					t.Errorf("%s was not extracted: %v", name, err)
+					continue
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("%s has %d bytes that differ from the %d written", name, len(got), len(want))
+				}
+			}
+		})
+	}
+}
+
+func TestWriterTooLarge(t *testing.T) {
+	rpfPath := filepath.Join(t.TempDir(), "cars.rpf")
+	w := &Writer{}
+	w.AddFile("stream/adder.yft", randomData(5, 9<<20))
+	w.AddFile("stream/t20.yft", randomData(6, 9<<20))
+	w.AddFile("stream/t20.ytd", randomData(7, 9<<20))
+	if err := w.Write(rpfPath); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("Write() of an archive past 16 MB = %v, want an error", err)
+	}
+	if _, err := os.Stat(rpfPath); !os.IsNotExist(err) {
+		t.Errorf("Write() left %s behind after failing", rpfPath)
+	}
+}
+
+func TestWriterAddAndRemoveFile(t *testing.T) {
+	rpfPath := filepath.Join(t.TempDir(), "cars.rpf")
+	w := &Writer{}
+	w.AddFile("stream/adder.ytd", []byte("old textures"))
+	w.AddFile("/stream/adder.ytd", []byte("new textures"))
+	w.AddFile("merger-cache.json", []byte("{}"))
+	w.RemoveFile("merger-cache.json")
+	w.RemoveFile("missing.txt")
+	if err := w.Write(rpfPath); err != nil {
+		t.Fatalf("Write() = %v", err)
+	}
+
+	e := &Extractor{CachePath: filepath.Join(t.TempDir(), "cars")}
+	if err := e.Extract(rpfPath); err != nil {
+		t.Fatalf("Extract() = %v", err)
+	}
+	if len(e.Extracted) != 1 {
+		t.Fatalf("Extracted = %v, want only stream/adder.ytd", e.Extracted)
+	}
+	got, err := os.ReadFile(filepath.Join(e.CachePath, "stream", "adder.ytd"))
+	if err != nil || string(got) != "new textures" {
+		t.Errorf("stream/adder.ytd = %q, %v, want the file added last", got, err)
+	}
+}
+
+func TestWriterAddDirectory(t *testing.T) {
+	files := map[string][]byte{
+		"fxmanifest.lua":   []byte("fx_version 'cerulean'\n"),
+		"stream/adder.yft": randomData(8, 64<<10),
+		"stream/adder.ytd": bytes.Repeat([]byte("adder textures "), 1000),
+	}
+	resource := t.TempDir()
+	for name, data := range files {
+		full := filepath.Join(resource, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, compress := range []bool{false, true} {
+		rpfPath := filepath.Join(t.TempDir(), "cars.rpf")
+		w := &Writer{Compress: compress}
+		if err := w.AddDirectory(resource); err != nil {
+			t.Fatalf("AddDirectory() = %v", err)
+		}
+		if err := w.Write(rpfPath); err != nil {
+			t.Fatalf("Write() with compress %v = %v", compress, err)
+		}
+
+		e := &Extractor{CachePath: filepath.Join(t.TempDir(), "cars")}
+		if err := e.Extract(rpfPath); err != nil {
+			t.Fatalf("Extract() = %v", err)
+		}
+		for name, want := range files {
+			got, err := os.ReadFile(filepath.Join(e.CachePath, filepath.FromSlash(name)))
+			if err != nil || !bytes.Equal(got, want) {
+				t.Errorf("%s with compress %v does not match the file packed: %v", name, compress, err)
+			}
+		}
+	}
+}
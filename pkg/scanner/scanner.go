@@ -0,0 +1,226 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/dft"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/ignore"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/typeidentifier"
+	poolutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/pool"
+	sliceutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/slice"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/validator"
+	"github.com/charmbracelet/log"
+)
+
+type Result struct {
+	StreamFiles []dft.StreamFile
+	DataFiles   []dft.DataFile
+	AudioFiles  []dft.AudioFile
+	Skipped     []dft.SkippedFile
+}
+
+type Scanner interface {
+	Scan() (*Result, error)
+	ScanRoot(root string) (*Result, error)
+	SetLogger(logger *log.Logger)
+}
+
+type scanner struct {
+	Flags          flags.Flags
+	Validator      validator.Validator
+	TypeIdentifier typeidentifier.TypeIdentifier
+	Logger         *log.Logger
+}
+
+func New(_flags flags.Flags) Scanner {
+	return &scanner{
+		Flags:          _flags,
+		Validator:      validator.New(),
+		TypeIdentifier: typeidentifier.New(),
+		Logger:         log.Default(),
+	}
+}
+
+// SetLogger makes the scanner log to logger instead of the default logger.
+func (s *scanner) SetLogger(logger *log.Logger) {
+	s.Logger = logger
+}
+
+// Scan walks the input path and sorts every stream, data and audio file it accepts.
+func (s *scanner) Scan() (*Result, error) {
+	return s.ScanRoot(s.Flags.InputPath)
+}
+
+// ScanRoot scans like Scan, but walks root instead of the input path. The top level folders
+// are walked and the files identified by a pool of Flags.Concurrency workers, but the result
+// is in walk order whatever the concurrency.
+func (s *scanner) ScanRoot(root string) (*Result, error) {
+	excludes := &ignore.Matcher{}
+	excludes.Add("", "config", s.Flags.Exclude)
+	includes := &ignore.Matcher{}
+	includes.Add("", "config", s.Flags.Include)
+	if err := excludes.AddFile("", filepath.Join(root, ignore.FileName)); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	workers := poolutils.Workers(s.Flags.Concurrency)
+
+	// Every top level entry is walked on its own, with its own copy of the ignore rules
+	walked := make([][]*item, len(entries))
+	err = poolutils.Run(workers, len(entries), func(i int) error {
+		entryItems, err := s.walk(root, filepath.Join(root, entries[i].Name()), excludes.Clone(), includes)
+		walked[i] = entryItems
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	var items []*item
+	for _, entryItems := range walked {
+		items = append(items, entryItems...)
+	}
+
+	err = poolutils.Run(workers, len(items), func(i int) error {
+		return s.classify(items[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for _, item := range items {
+		switch {
+		case item.skipped != nil:
+			result.Skipped = append(result.Skipped, *item.skipped)
+		case item.audio != nil:
+			result.AudioFiles = append(result.AudioFiles, *item.audio)
+		case item.stream != nil:
+			result.StreamFiles = append(result.StreamFiles, *item.stream)
+		case item.data != nil:
+			result.DataFiles = append(result.DataFiles, *item.data)
+		}
+	}
+	return result, nil
+}
+
+// item is a file the walk found, or a file or folder it skipped. classify fills in what the file is.
+type item struct {
+	path    string
+	name    string
+	skipped *dft.SkippedFile
+	audio   *dft.AudioFile
+	stream  *dft.StreamFile
+	data    *dft.DataFile
+}
+
+// walk lists the files below start that aren't ignored or excluded.
+func (s *scanner) walk(root string, start string, excludes *ignore.Matcher, includes *ignore.Matcher) ([]*item, error) {
+	var items []*item
+	err := filepath.Walk(start, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if ignored, rule := excludes.Match(rel, f.IsDir()); ignored {
+			reason := fmt.Sprintf("excluded by pattern %q from %s", rule.Pattern, rule.Source)
+			s.Logger.Debug("Skipping ignored path", "path", path, "pattern", rule.Pattern, "source", rule.Source)
+			items = append(items, &item{path: path, skipped: &dft.SkippedFile{Path: path, Reason: reason}})
+			if f.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if f.IsDir() {
+			if s.isExcluded(root, path) {
+				s.Logger.Debug("Skipping excluded car", "path", path)
+				items = append(items, &item{path: path, skipped: &dft.SkippedFile{Path: path, Reason: "car is excluded in the settings"}})
+				return filepath.SkipDir
+			}
+			return excludes.AddFile(rel, filepath.Join(path, ignore.FileName))
+		}
+		if f.Name() == ignore.FileName {
+			return nil
+		}
+		if matched, _ := includes.Match(rel, false); !includes.Empty() && !matched {
+			s.Logger.Debug("Skipping path that no include pattern matches", "path", path)
+			items = append(items, &item{path: path, skipped: &dft.SkippedFile{Path: path, Reason: "not matched by any include pattern"}})
+			return nil
+		}
+		items = append(items, &item{path: path, name: f.Name()})
+		return nil
+	})
+	return items, err
+}
+
+// classify sorts a file into audio, stream or data, or records why it is skipped.
+func (s *scanner) classify(item *item) error {
+	if item.skipped != nil {
+		return nil
+	}
+	path, name := item.path, item.name
+
+	if s.Validator.IsValidAudioFile(name) || s.Validator.IsValidAudioDataFile(name) {
+		dlcFolder := filepath.Base(filepath.Dir(path))
+		if strings.HasPrefix(dlcFolder, "dlc_") {
+			dlcFolder = strings.TrimPrefix(dlcFolder, "dlc_")
+		}
+
+		item.audio = &dft.AudioFile{
+			Path:      path,
+			Name:      name,
+			IsConfig:  s.Validator.IsValidAudioDataFile(name),
+			DLCFolder: dlcFolder,
+		}
+		return nil
+	}
+	if s.Validator.IsValidStreamFile(name) {
+		item.stream = &dft.StreamFile{
+			Path: path,
+			Name: name,
+		}
+		return nil
+	}
+	if s.Validator.IsValidDataFile(name) {
+		_type, err := s.TypeIdentifier.IdentifyDataFileType(path)
+		if err != nil {
+			return err
+		}
+
+		if _type != dft.INVALID {
+			item.data = &dft.DataFile{
+				Path: path,
+				Name: name,
+				Type: _type,
+			}
+		} else if tag, _ := s.TypeIdentifier.RootTag(path); tag != "" {
+			item.skipped = &dft.SkippedFile{Path: path, Reason: "unknown root tag " + tag, UnknownTag: tag}
+		} else {
+			item.skipped = &dft.SkippedFile{Path: path, Reason: "not a valid XML file"}
+		}
+		return nil
+	}
+	item.skipped = &dft.SkippedFile{Path: path, Reason: "unsupported file extension"}
+	return nil
+}
+
+// isExcluded reports whether dir is a top level folder of root listed in ExcludedCars.
+func (s *scanner) isExcluded(root string, dir string) bool {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." || strings.Contains(filepath.ToSlash(rel), "/") {
+		return false
+	}
+	return sliceutils.ContainsElement(s.Flags.ExcludedCars, rel)
+}
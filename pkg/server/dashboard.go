@@ -0,0 +1,178 @@
+package server
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/carfinder"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/config"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/inspector"
+	sliceutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/slice"
+	"github.com/charmbracelet/log"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// DashboardCar is a car as shown on the dashboard.
+type DashboardCar struct {
+	*carfinder.Car
+	Excluded bool
+}
+
+type carsResponse struct {
+	InputPath      string
+	ExtraInputs    []string
+	OutputPath     string
+	Input          []DashboardCar
+	Unassigned     []string
+	Output         []DashboardCar
+	OutputWarnings []string
+	ExcludedCars   []string
+}
+
+type exclusionRequest struct {
+	Folder string
+}
+
+func (s *server) handleDashboard() http.Handler {
+	web, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServerFS(web)
+}
+
+// handleCars lists the cars of the input paths, excluded ones included, and of the last output.
+func (s *server) handleCars(w http.ResponseWriter, r *http.Request) {
+	appFlags := s.flags()
+	response := carsResponse{
+		InputPath:    appFlags.InputPath,
+		ExtraInputs:  appFlags.ExtraInputs,
+		OutputPath:   appFlags.OutputPath,
+		Input:        []DashboardCar{},
+		Output:       []DashboardCar{},
+		ExcludedCars: appFlags.ExcludedCars,
+	}
+
+	if appFlags.InputPath != "" {
+		scanFlags := appFlags
+		scanFlags.ExcludedCars = nil
+		inventory, err := inspector.New(scanFlags).Inspect()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		for _, car := range inventory.Cars {
+			response.Input = append(response.Input, DashboardCar{
+				Car:      car,
+				Excluded: sliceutils.ContainsElement(appFlags.ExcludedCars, car.Folder),
+			})
+		}
+		response.Unassigned = inventory.Unassigned
+	}
+
+	if _, err := os.Stat(appFlags.OutputPath); appFlags.OutputPath != "" && err == nil {
+		cars, _, err := carfinder.ResourceCars(appFlags.OutputPath)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		for _, car := range cars {
+			response.Output = append(response.Output, DashboardCar{Car: car})
+		}
+		response.OutputWarnings = outputWarnings(appFlags)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// outputWarnings returns the warnings carfinder reports for a merged output.
+func outputWarnings(appFlags flags.Flags) []string {
+	carFinder := carfinder.New(appFlags)
+	dataFileCars, dataErr := carFinder.FindDataFileCars()
+	streamFileCars, streamErr := carFinder.FindStreamFileCars()
+	if dataErr != nil || streamErr != nil {
+		return []string{"output is not a merged resource: " + errors.Join(dataErr, streamErr).Error()}
+	}
+
+	var warnings []string
+	_, noStreamCars, noDataCars := carfinder.ClassifyCars(dataFileCars, streamFileCars)
+	for _, car := range noStreamCars {
+		warnings = append(warnings, car+" has no stream files")
+	}
+	for _, car := range noDataCars {
+		warnings = append(warnings, car+" has no data files")
+	}
+	return warnings
+}
+
+func (s *server) handleExclude(w http.ResponseWriter, r *http.Request) {
+	request := exclusionRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.Folder == "" {
+		writeError(w, http.StatusBadRequest, errors.New("request needs a Folder"))
+		return
+	}
+	s.setExcluded(w, request.Folder, true)
+}
+
+func (s *server) handleInclude(w http.ResponseWriter, r *http.Request) {
+	s.setExcluded(w, r.PathValue("folder"), false)
+}
+
+// setExcluded adds or removes a car folder from ExcludedCars, both for the next merges of
+// this server and in its profile of config.json.
+func (s *server) setExcluded(w http.ResponseWriter, folder string, excluded bool) {
+	// Only the exclusions are written, so flags given on the command line stay out of the config
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if cfg == nil {
+		// Without a config, a new one starts from the settings the server runs with, so first
+		// time setup isn't skipped for a profile without paths
+		s.mu.Lock()
+		serverFlags := s.Flags
+		s.mu.Unlock()
+		if serverFlags.InputPath == "" || serverFlags.OutputPath == "" {
+			writeError(w, http.StatusConflict, errors.New("there is no config.json to save the exclusion to"))
+			return
+		}
+		cfg = config.New(&serverFlags)
+	}
+	savedFlags, err := cfg.Profile(s.Profile)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	savedFlags.ExcludedCars = updateExcluded(savedFlags.ExcludedCars, folder, excluded)
+	if err := config.SaveConfig(cfg); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	s.mu.Lock()
+	s.Flags.ExcludedCars = updateExcluded(s.Flags.ExcludedCars, folder, excluded)
+	excludedCars := s.Flags.ExcludedCars
+	s.mu.Unlock()
+
+	log.Info("Updated excluded cars", "folder", folder, "excluded", excluded)
+	writeJSON(w, http.StatusOK, map[string][]string{"ExcludedCars": excludedCars})
+}
+
+func updateExcluded(excludedCars []string, folder string, excluded bool) []string {
+	excludedCars = slices.DeleteFunc(slices.Clone(excludedCars), func(excludedCar string) bool {
+		return excludedCar == folder
+	})
+	if excluded {
+		excludedCars = append(excludedCars, folder)
+	}
+	return excludedCars
+}
@@ -0,0 +1,203 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/report"
+)
+
+type Status string
+
+const (
+	QUEUED    Status = "queued"
+	RUNNING   Status = "running"
+	SUCCEEDED Status = "succeeded"
+	FAILED    Status = "failed"
+	CANCELLED Status = "cancelled"
+)
+
+// maxEvents is how many events a job keeps, older ones are dropped so a long running server
+// doesn't keep every log line of every merge.
+const maxEvents = 5000
+
+func (s Status) Done() bool {
+	return s == SUCCEEDED || s == FAILED || s == CANCELLED
+}
+
+// Event is one server-sent event of a job: a log line, a progress update or a status change.
+type Event struct {
+	Type string
+	Data string
+}
+
+// JobInfo is the JSON view of a job.
+type JobInfo struct {
+	ID         string
+	Status     Status
+	Error      string `json:",omitempty"`
+	InputPath  string
+	OutputPath string
+	CreatedAt  time.Time
+	StartedAt  *time.Time `json:",omitempty"`
+	FinishedAt *time.Time `json:",omitempty"`
+}
+
+type Job struct {
+	ID    string
+	Flags flags.Flags
+
+	mu         sync.Mutex
+	status     Status
+	err        string
+	createdAt  time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+	report     *report.Report
+	events     []Event
+	dropped    int // events dropped from the start of events
+	changed    chan struct{}
+	partial    []byte
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newJob(id string, _flags flags.Flags) *Job {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Job{
+		ID:        id,
+		Flags:     _flags,
+		status:    QUEUED,
+		createdAt: time.Now(),
+		changed:   make(chan struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+func (j *Job) Info() JobInfo {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	info := JobInfo{
+		ID:         j.ID,
+		Status:     j.status,
+		Error:      j.err,
+		InputPath:  j.Flags.InputPath,
+		OutputPath: j.Flags.OutputPath,
+		CreatedAt:  j.createdAt,
+	}
+	if !j.startedAt.IsZero() {
+		startedAt := j.startedAt
+		info.StartedAt = &startedAt
+	}
+	if !j.finishedAt.IsZero() {
+		finishedAt := j.finishedAt
+		info.FinishedAt = &finishedAt
+	}
+	return info
+}
+
+func (j *Job) Status() Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+func (j *Job) Report() *report.Report {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.report
+}
+
+// Cancel stops a running job between files, or drops a queued one before it starts.
+func (j *Job) Cancel() {
+	j.cancel()
+	j.mu.Lock()
+	queued := j.status == QUEUED
+	j.mu.Unlock()
+	if queued {
+		j.finish(CANCELLED, nil, nil)
+	}
+}
+
+func (j *Job) start() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status != QUEUED {
+		return false
+	}
+	j.status = RUNNING
+	j.startedAt = time.Now()
+	j.emitLocked(Event{Type: "status", Data: string(RUNNING)})
+	return true
+}
+
+func (j *Job) finish(status Status, err error, r *report.Report) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.status.Done() {
+		return
+	}
+	if len(j.partial) > 0 {
+		j.emitLocked(Event{Type: "log", Data: string(j.partial)})
+		j.partial = nil
+	}
+	j.status = status
+	j.finishedAt = time.Now()
+	j.report = r
+	if err != nil {
+		j.err = err.Error()
+	}
+	j.emitLocked(Event{Type: "status", Data: string(status)})
+}
+
+func (j *Job) emit(event Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.emitLocked(event)
+}
+
+func (j *Job) emitLocked(event Event) {
+	j.events = append(j.events, event)
+	if len(j.events) > maxEvents {
+		drop := len(j.events) - maxEvents*3/4
+		j.events = slices.Clone(j.events[drop:])
+		j.dropped += drop
+	}
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// eventsSince returns the events after the first n that are still kept, the number of
+// events after them, a channel that is closed when more arrive and whether the job is finished.
+func (j *Job) eventsSince(n int) ([]Event, int, <-chan struct{}, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	n = max(n, j.dropped)
+	var events []Event
+	if n-j.dropped < len(j.events) {
+		events = append(events, j.events[n-j.dropped:]...)
+	}
+	return events, j.dropped + len(j.events), j.changed, j.status.Done()
+}
+
+// Write turns log output into one log event per line.
+func (j *Job) Write(p []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.partial = append(j.partial, p...)
+	for {
+		i := bytes.IndexByte(j.partial, '\n')
+		if i < 0 {
+			break
+		}
+		j.emitLocked(Event{Type: "log", Data: string(j.partial[:i])})
+		j.partial = j.partial[i+1:]
+	}
+	return len(p), nil
+}
@@ -0,0 +1,417 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/config"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/logger"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/merger"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/report"
+	"github.com/charmbracelet/log"
+)
+
+const DefaultAddr = "127.0.0.1:8686"
+
+// maxFinishedJobs is how many finished merges the server remembers, older ones are forgotten.
+const maxFinishedJobs = 50
+
+type Server interface {
+	Serve(ctx context.Context, addr string) error
+	Handler() http.Handler
+}
+
+type server struct {
+	Flags   flags.Flags
+	Profile string // profile of config.json the flags came from, empty without a config
+	addr    string // address Serve listens on, also accepted as Host besides localhost
+
+	mu     sync.Mutex
+	jobs   map[string]*Job
+	order  []string
+	nextID int
+	queue  chan *Job
+	last   *report.Report
+	closed bool // set on shutdown, no merges are queued after it
+}
+
+// mergeRequest picks the settings of a single merge. Paths and Clean only come from
+// config.json, so a request can't make the server write to or delete another folder.
+type mergeRequest struct {
+	Profile *string // merge with this profile of config.json instead of the server flags
+}
+
+func New(_flags flags.Flags, profile string) Server {
+	return &server{
+		Flags:   _flags,
+		Profile: profile,
+		jobs:    make(map[string]*Job),
+		queue:   make(chan *Job, 64),
+	}
+}
+
+// Serve runs the HTTP API on addr until ctx is done. Merges run one at a time in the
+// order they were requested, so two merges never write to the same output at once.
+// When ctx is done the running merge is cancelled and waited for, and queued ones are cancelled.
+func (s *server) Serve(ctx context.Context, addr string) error {
+	s.addr = addr
+	worked := make(chan struct{})
+	go func() {
+		defer close(worked)
+		s.work(ctx)
+	}()
+
+	httpServer := &http.Server{Addr: addr, Handler: s.Handler()}
+	go func() {
+		<-ctx.Done()
+		// Finished jobs also end their event streams, so Shutdown doesn't wait on them
+		s.cancelJobs()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Info("Serving API", "addr", "http://"+addr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	<-worked
+	return nil
+}
+
+func (s *server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/merges", s.guard(s.handleStart))
+	mux.HandleFunc("GET /api/merges", s.handleList)
+	mux.HandleFunc("GET /api/merges/{id}", s.handleGet)
+	mux.HandleFunc("GET /api/merges/{id}/events", s.handleEvents)
+	mux.HandleFunc("DELETE /api/merges/{id}", s.guard(s.handleCancel))
+	mux.HandleFunc("GET /api/report", s.handleReport)
+	mux.HandleFunc("GET /api/cars", s.handleCars)
+	mux.HandleFunc("POST /api/exclusions", s.guard(s.handleExclude))
+	mux.HandleFunc("DELETE /api/exclusions/{folder}", s.guard(s.handleInclude))
+	mux.Handle("GET /", s.handleDashboard())
+	return s.checkHost(mux)
+}
+
+// checkHost rejects requests for any other host than the server, so a page whose domain
+// is rebound to 127.0.0.1 can't talk to the API.
+func (s *server) checkHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.localHost(r.Host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %q is not allowed", r.Host))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// guard only lets requests that change something through when they come from the dashboard
+// or a local tool: a browser sends the Origin of the page, which has to be the server itself,
+// and a POST needs a JSON body, which no page can send cross-site without asking first.
+func (s *server) guard(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			originURL, err := url.Parse(origin)
+			if err != nil || originURL.Host != r.Host {
+				writeError(w, http.StatusForbidden, fmt.Errorf("origin %q is not allowed", origin))
+				return
+			}
+		}
+		if r.Method == http.MethodPost {
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				writeError(w, http.StatusUnsupportedMediaType, errors.New("request needs Content-Type application/json"))
+				return
+			}
+		}
+		next(w, r)
+	}
+}
+
+// localHost reports whether host, as in the Host header, names this server.
+func (s *server) localHost(host string) bool {
+	name, _, err := net.SplitHostPort(host)
+	if err != nil {
+		name = host
+	}
+	if listenHost, _, err := net.SplitHostPort(s.addr); err == nil && listenHost != "" && name == listenHost {
+		return true
+	}
+	if name == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(name)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (s *server) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-s.queue:
+			s.run(job)
+		}
+	}
+}
+
+// cancelJobs cancels every job that isn't finished and stops queueing new ones.
+func (s *server) cancelJobs() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for _, id := range s.order {
+		s.jobs[id].Cancel()
+	}
+}
+
+func (s *server) run(job *Job) {
+	if !job.start() {
+		return
+	}
+
+	// The job logs to its own logger, so requests served meanwhile stay out of its events
+	jobLogger := logger.New(io.MultiWriter(logger.Output(), job), job.Flags.Verbose)
+	carsMerger := merger.New(job.Flags)
+	carsMerger.SetLogger(jobLogger)
+	carsMerger.OnProgress(func(progress merger.Progress) {
+		data, _ := json.Marshal(progress)
+		job.emit(Event{Type: "progress", Data: string(data)})
+	})
+
+	err := carsMerger.MergeContext(job.ctx)
+	status := SUCCEEDED
+	switch {
+	case errors.Is(err, context.Canceled):
+		status = CANCELLED
+		jobLogger.Warn("Merge cancelled", "job", job.ID)
+	case err != nil:
+		status = FAILED
+		jobLogger.Error("Merge failed", "job", job.ID, "err", err)
+	}
+
+	if r := carsMerger.Report(); r != nil {
+		s.mu.Lock()
+		s.last = r
+		s.mu.Unlock()
+	}
+	job.finish(status, err, carsMerger.Report())
+}
+
+func (s *server) handleStart(w http.ResponseWriter, r *http.Request) {
+	request := mergeRequest{}
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+			return
+		}
+	}
+
+	jobFlags := s.flags()
+	if request.Profile != nil {
+		profileFlags, err := loadProfile(*request.Profile)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		jobFlags = *profileFlags
+	}
+	if err := config.CheckPaths(jobFlags); err != nil {
+		var paths *config.PathsError
+		errors.As(err, &paths)
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"Error": err.Error(), "Problems": paths.Problems})
+		return
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		writeError(w, http.StatusServiceUnavailable, errors.New("the server is shutting down"))
+		return
+	}
+	s.nextID++
+	job := newJob(strconv.Itoa(s.nextID), jobFlags)
+	s.jobs[job.ID] = job
+	s.order = append(s.order, job.ID)
+	s.pruneJobs()
+	s.mu.Unlock()
+
+	select {
+	case s.queue <- job:
+	default:
+		job.finish(FAILED, errors.New("too many queued merges"), nil)
+		writeError(w, http.StatusServiceUnavailable, errors.New("too many queued merges"))
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, job.Info())
+}
+
+// pruneJobs forgets the oldest finished jobs beyond maxFinishedJobs. s.mu must be held.
+func (s *server) pruneJobs() {
+	finished := 0
+	kept := make([]string, 0, len(s.order))
+	for i := len(s.order) - 1; i >= 0; i-- {
+		id := s.order[i]
+		if s.jobs[id].Status().Done() {
+			if finished++; finished > maxFinishedJobs {
+				delete(s.jobs, id)
+				continue
+			}
+		}
+		kept = append(kept, id)
+	}
+	slices.Reverse(kept)
+	s.order = kept
+}
+
+func (s *server) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	infos := make([]JobInfo, 0, len(s.order))
+	for _, id := range s.order {
+		infos = append(infos, s.jobs[id].Info())
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *server) handleGet(w http.ResponseWriter, r *http.Request) {
+	job := s.job(w, r)
+	if job == nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, job.Info())
+}
+
+func (s *server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	job := s.job(w, r)
+	if job == nil {
+		return
+	}
+	if job.Status().Done() {
+		writeError(w, http.StatusConflict, fmt.Errorf("job %s already finished", job.ID))
+		return
+	}
+	job.Cancel()
+	writeJSON(w, http.StatusAccepted, job.Info())
+}
+
+// handleEvents streams the log lines, progress and status of a job as server-sent events,
+// starting with everything that happened before the client connected.
+func (s *server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	job := s.job(w, r)
+	if job == nil {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	sent := 0
+	for {
+		events, next, changed, done := job.eventsSince(sent)
+		for _, event := range events {
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, event.Data)
+		}
+		sent = next
+		flusher.Flush()
+		if done {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+		}
+	}
+}
+
+// handleReport returns the report of the last merge, falling back to the report file of the configured output.
+func (s *server) handleReport(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	last := s.last
+	outputPath := s.Flags.OutputPath
+	s.mu.Unlock()
+
+	if last == nil && outputPath != "" {
+		if loaded, err := report.Load(report.PathFor(outputPath)); err == nil {
+			last = loaded
+		}
+	}
+	if last == nil {
+		writeError(w, http.StatusNotFound, errors.New("no merge report yet"))
+		return
+	}
+	writeJSON(w, http.StatusOK, last)
+}
+
+func loadProfile(name string) (*flags.Flags, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if cfg == nil {
+		return nil, fmt.Errorf("profile %q requested, but there is no config.json", name)
+	}
+	profileFlags, err := cfg.Profile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	profileFlags.InputPath = config.ResolvePath(profileFlags.InputPath)
+	profileFlags.ExtraInputs = config.ResolvePaths(profileFlags.ExtraInputs)
+	profileFlags.OutputPath = config.ResolvePath(profileFlags.OutputPath)
+	profileFlags.Overrides = config.ResolvePath(profileFlags.Overrides)
+	return profileFlags, nil
+}
+
+func (s *server) flags() flags.Flags {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Flags
+}
+
+func (s *server) job(w http.ResponseWriter, r *http.Request) *Job {
+	s.mu.Lock()
+	job := s.jobs[r.PathValue("id")]
+	s.mu.Unlock()
+
+	if job == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %s not found", r.PathValue("id")))
+	}
+	return job
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"Error": err.Error()})
+}
@@ -0,0 +1,156 @@
+const $ = (id) => document.getElementById(id);
+
+let currentJob = null;
+let events = null;
+
+async function api(method, path, body) {
+  const response = await fetch(path, {
+    method,
+    headers: body ? { "Content-Type": "application/json" } : {},
+    body: body ? JSON.stringify(body) : undefined,
+  });
+  const data = await response.json();
+  if (!response.ok) {
+    throw new Error(data.Error || response.statusText);
+  }
+  return data;
+}
+
+function cell(row, text, className) {
+  const td = row.insertCell();
+  td.textContent = text || "-";
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+function baseNames(paths) {
+  return (paths || []).map((path) => path.split(/[\\/]/).pop()).join(", ");
+}
+
+function carWarnings(car) {
+  return (car.Missing || []).map((piece) => "missing " + piece).join(", ");
+}
+
+async function loadCars() {
+  const cars = await api("GET", "/api/cars");
+  const inputs = [cars.InputPath, ...(cars.ExtraInputs || [])].filter(Boolean).join(", ");
+  $("paths").textContent = `${inputs || "no input"} → ${cars.OutputPath || "no output"}`;
+
+  const input = $("input-cars");
+  input.replaceChildren();
+  for (const car of cars.Input) {
+    const row = input.insertRow();
+    row.className = car.Excluded ? "excluded" : "";
+    cell(row, car.Model);
+    cell(row, car.Folder);
+    cell(row, baseNames(car.StreamFiles));
+    cell(row, (car.DataTypes || []).join(", ").toLowerCase());
+    cell(row, (car.AudioPacks || []).join(", "));
+    cell(row, carWarnings(car), "warning");
+
+    const button = document.createElement("button");
+    button.textContent = car.Excluded ? "Include" : "Exclude";
+    button.onclick = () => setExcluded(car.Folder, !car.Excluded);
+    row.insertCell().append(button);
+  }
+  $("unassigned").textContent = cars.Unassigned && cars.Unassigned.length
+    ? "Files that belong to no car: " + baseNames(cars.Unassigned)
+    : "";
+
+  const output = $("output-cars");
+  output.replaceChildren();
+  for (const car of cars.Output) {
+    const row = output.insertRow();
+    cell(row, car.Model);
+    cell(row, baseNames(car.StreamFiles));
+    cell(row, (car.DataTypes || []).join(", ").toLowerCase());
+    cell(row, (car.AudioPacks || []).join(", "));
+    cell(row, carWarnings(car), "warning");
+  }
+
+  const warnings = $("output-warnings");
+  warnings.replaceChildren();
+  for (const warning of cars.OutputWarnings || []) {
+    const item = document.createElement("li");
+    item.textContent = warning;
+    warnings.append(item);
+  }
+}
+
+async function loadJobs() {
+  const jobs = await api("GET", "/api/merges");
+  const body = $("jobs");
+  body.replaceChildren();
+  for (const job of jobs.reverse()) {
+    const row = body.insertRow();
+    cell(row, job.ID);
+    cell(row, job.Status, job.Status);
+    cell(row, job.StartedAt && new Date(job.StartedAt).toLocaleString());
+    cell(row, job.FinishedAt && new Date(job.FinishedAt).toLocaleString());
+    cell(row, job.Error, "failed");
+  }
+}
+
+async function setExcluded(folder, excluded) {
+  try {
+    if (excluded) {
+      await api("POST", "/api/exclusions", { Folder: folder });
+    } else {
+      await api("DELETE", "/api/exclusions/" + encodeURIComponent(folder));
+    }
+    await loadCars();
+  } catch (err) {
+    alert(err.message);
+  }
+}
+
+function follow(job) {
+  currentJob = job;
+  $("log").textContent = "";
+  $("start").disabled = true;
+  $("cancel").disabled = false;
+
+  events = new EventSource(`/api/merges/${job.ID}/events`);
+  events.addEventListener("log", (event) => {
+    $("log").textContent += event.data + "\n";
+    $("log").scrollTop = $("log").scrollHeight;
+  });
+  events.addEventListener("progress", (event) => {
+    const progress = JSON.parse(event.data);
+    $("progress").max = progress.Total || 1;
+    $("progress").value = progress.Done;
+    $("stage").textContent = `${progress.Stage} ${progress.Done}/${progress.Total}`;
+  });
+  events.addEventListener("status", (event) => {
+    loadJobs();
+    if (["succeeded", "failed", "cancelled"].includes(event.data)) {
+      events.close();
+      currentJob = null;
+      $("start").disabled = false;
+      $("cancel").disabled = true;
+      $("stage").textContent = event.data;
+      loadCars();
+    }
+  });
+}
+
+$("start").onclick = async () => {
+  try {
+    const job = await api("POST", "/api/merges", {});
+    follow(job);
+    loadJobs();
+  } catch (err) {
+    alert(err.message);
+  }
+};
+
+$("cancel").onclick = async () => {
+  if (currentJob) {
+    await api("DELETE", `/api/merges/${currentJob.ID}`).catch((err) => alert(err.message));
+  }
+};
+
+loadCars().catch((err) => ($("paths").textContent = err.message));
+loadJobs();
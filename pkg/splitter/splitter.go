@@ -0,0 +1,110 @@
+package splitter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/carfinder"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/exitcode"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/manifestgen"
+	fileutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/file"
+	sliceutils "github.com/ItzDabbzz/FiveMCarsMerger/pkg/utils/slice"
+	"github.com/charmbracelet/log"
+)
+
+type Splitter interface {
+	Split(resourcePath string, destination string, models []string) ([]string, error)
+}
+
+type splitter struct {
+	Flags flags.Flags
+}
+
+func New(_flags flags.Flags) Splitter {
+	return &splitter{Flags: _flags}
+}
+
+// Split writes one standalone resource per car of a merged resource into destination.
+// When models is empty every car is split out. It returns the written resource paths.
+func (s *splitter) Split(resourcePath string, destination string, models []string) ([]string, error) {
+	cars, unassigned, err := carfinder.ResourceCars(resourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read merged resource: %w", err)
+	}
+
+	models = slices.Clone(models)
+	for i, model := range models {
+		models[i] = strings.ToLower(model)
+		found := false
+		for _, car := range cars {
+			if car.Model == models[i] {
+				found = true
+			}
+		}
+		if !found {
+			return nil, exitcode.Validationf("car %s not found in %s", model, resourcePath)
+		}
+	}
+
+	if len(unassigned) > 0 {
+		log.Warn("Files that belong to no car are left out", "files", unassigned)
+	}
+
+	var written []string
+	for _, car := range cars {
+		if len(models) > 0 && !sliceutils.ContainsElement(models, car.Model) {
+			continue
+		}
+		if len(car.DataFiles) == 0 {
+			log.Warn("Skipping car without data files", "car", car.Model)
+			continue
+		}
+
+		carPath := filepath.Join(destination, car.Model)
+		if err := s.prepareDirectory(carPath); err != nil {
+			return written, err
+		}
+
+		log.Info("Splitting car", "car", car.Model, "path", carPath)
+		files := append(append(append([]string{}, car.StreamFiles...), car.DataFiles...), car.AudioFiles...)
+		for _, file := range files {
+			rel, err := filepath.Rel(resourcePath, file)
+			if err != nil {
+				return written, err
+			}
+			destPath := filepath.Join(carPath, rel)
+			if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+				return written, err
+			}
+			log.Debug("Copying file", "from", file, "to", destPath)
+			if _, err := fileutils.CopyFile(file, destPath); err != nil {
+				return written, fmt.Errorf("failed to copy file %s: %w", file, err)
+			}
+		}
+
+		carFlags := s.Flags
+		carFlags.OutputPath = carPath
+		if err := manifestgen.New(carFlags).Generate(); err != nil {
+			return written, err
+		}
+		written = append(written, carPath)
+	}
+
+	return written, nil
+}
+
+func (s *splitter) prepareDirectory(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		if !s.Flags.Clean {
+			return fmt.Errorf("%s already exists, remove it or enable clean", path)
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return os.MkdirAll(path, 0755)
+}
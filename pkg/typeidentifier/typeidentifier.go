@@ -10,6 +10,7 @@ import (
 
 type TypeIdentifier interface {
 	IdentifyDataFileType(path string) (dft.DataFileType, error)
+	RootTag(path string) (string, error)
 }
 
 type typeIdentifier struct {
@@ -19,19 +20,24 @@ func New() TypeIdentifier {
 	return &typeIdentifier{}
 }
 
-func (ti *typeIdentifier) IdentifyDataFileType(path string) (dft.DataFileType, error) {
+// RootTag returns the name of the root element of an XML file, or "" when it has none.
+func (ti *typeIdentifier) RootTag(path string) (string, error) {
 	xmlFile, err := os.Open(path)
 	if err != nil {
-		return dft.INVALID, err
+		return "", err
 	}
 	byteValue, err := ioutil.ReadAll(xmlFile)
 	if err != nil {
-		return dft.INVALID, err
+		return "", err
 	}
 
 	defer xmlFile.Close()
 
-	startTag, err := xmlutils.GetStartTag(byteValue)
+	return xmlutils.GetStartTag(byteValue)
+}
+
+func (ti *typeIdentifier) IdentifyDataFileType(path string) (dft.DataFileType, error) {
+	startTag, err := ti.RootTag(path)
 	if err != nil {
 		return dft.INVALID, err
 	}
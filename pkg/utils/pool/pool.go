@@ -0,0 +1,60 @@
+package pool
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Workers returns concurrency, or the number of CPUs when it is below 1.
+func Workers(concurrency int) int {
+	if concurrency < 1 {
+		return runtime.NumCPU()
+	}
+	return concurrency
+}
+
+// Run calls fn for every index below n with at most workers calls at once. Once a call
+// fails, indices that haven't started are skipped, and the error of the lowest failed
+// index is returned. Callers keep results deterministic by storing them by index.
+func Run(workers int, n int, fn func(i int) error) error {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	errs := make([]error, n)
+	var next atomic.Int64
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !failed.Load() {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				if errs[i] = fn(i); errs[i] != nil {
+					failed.Store(true)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
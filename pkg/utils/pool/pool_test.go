@@ -0,0 +1,138 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+)
+
+func TestWorkers(t *testing.T) {
+	tests := []struct {
+		concurrency int
+		want        int
+	}{
+		{concurrency: -1, want: runtime.NumCPU()},
+		{concurrency: 0, want: runtime.NumCPU()},
+		{concurrency: 1, want: 1},
+		{concurrency: 8, want: 8},
+	}
+	for _, test := range tests {
+		if got := Workers(test.concurrency); got != test.want {
+			t.Errorf("Workers(%d) = %d, want %d", test.concurrency, got, test.want)
+		}
+	}
+}
+
+func TestRunVisitsEveryIndex(t *testing.T) {
+	tests := []struct {
+		workers int
+		n       int
+	}{
+		{workers: 1, n: 0},
+		{workers: 1, n: 10},
+		{workers: 4, n: 0},
+		{workers: 4, n: 3},
+		{workers: 4, n: 1000},
+		{workers: 64, n: 100},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%d workers %d items", test.workers, test.n), func(t *testing.T) {
+			results := make([]int, test.n)
+			var calls atomic.Int64
+			err := Run(test.workers, test.n, func(i int) error {
+				calls.Add(1)
+				results[i] = i * i
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Run() = %v", err)
+			}
+			if int(calls.Load()) != test.n {
+				t.Errorf("fn called %d times, want %d", calls.Load(), test.n)
+			}
+			for i, result := range results {
+				if result != i*i {
+					t.Errorf("results[%d] = %d, want %d", i, result, i*i)
+				}
+			}
+		})
+	}
+}
+
+func TestRunSequentialOrder(t *testing.T) {
+	var order []int
+	err := Run(1, 5, func(i int) error {
+		order = append(order, i)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("order = %v, want indices in order", order)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		workers int
+		failing []int
+		want    int
+	}{
+		{name: "sequential stops at first failure", workers: 1, failing: []int{3, 7}, want: 3},
+		{name: "parallel single failure", workers: 4, failing: []int{50}, want: 50},
+		{name: "parallel returns lowest failed index", workers: 4, failing: []int{0, 1, 2, 3}, want: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			failing := make(map[int]bool)
+			for _, i := range test.failing {
+				failing[i] = true
+			}
+			err := Run(test.workers, 100, func(i int) error {
+				if failing[i] {
+					return &indexError{i}
+				}
+				return nil
+			})
+
+			var indexErr *indexError
+			if !errors.As(err, &indexErr) {
+				t.Fatalf("Run() = %v, want an indexError", err)
+			}
+			if indexErr.i != test.want {
+				t.Errorf("Run() failed at index %d, want %d", indexErr.i, test.want)
+			}
+		})
+	}
+}
+
+func TestRunSkipsAfterFailure(t *testing.T) {
+	var calls atomic.Int64
+	err := Run(1, 100, func(i int) error {
+		calls.Add(1)
+		if i == 9 {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	if err == nil {
+		t.Fatal("Run() = nil, want an error")
+	}
+	if calls.Load() != 10 {
+		t.Errorf("fn called %d times, want 10", calls.Load())
+	}
+}
+
+type indexError struct {
+	i int
+}
+
+func (e *indexError) Error() string {
+	return fmt.Sprintf("index %d failed", e.i)
+}
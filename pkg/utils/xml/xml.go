@@ -2,6 +2,7 @@ package xml
 
 import (
 	"encoding/xml"
+	"fmt"
 	"io"
 	"strings"
 )
@@ -22,3 +23,97 @@ func GetStartTag(bytes []byte) (string, error) {
 		}
 	}
 }
+
+type node struct {
+	name     string
+	attrs    []xml.Attr
+	text     string
+	children []*node
+}
+
+// itemKeys are child elements whose value identifies an Item better than its position.
+var itemKeys = []string{"modelName", "handlingName", "kitName", "id"}
+
+// Flatten maps every element text and attribute of a document to a path such as
+// CHandlingDataMgr/HandlingData/Item[adderH]/fMass@value. List items are keyed by
+// their modelName or handlingName when they have one, and by position otherwise.
+func Flatten(bytes []byte) (map[string]string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(string(bytes)))
+	root := &node{}
+	stack := []*node{root}
+
+	for {
+		t, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		switch token := t.(type) {
+		case xml.StartElement:
+			child := &node{name: token.Name.Local, attrs: token.Attr}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, child)
+			stack = append(stack, child)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			current := stack[len(stack)-1]
+			current.text += string(token)
+		}
+	}
+
+	fields := make(map[string]string)
+	flattenChildren(root, "", fields)
+	return fields, nil
+}
+
+func flattenChildren(parent *node, prefix string, fields map[string]string) {
+	counts := make(map[string]int)
+	for _, child := range parent.children {
+		counts[child.name]++
+	}
+
+	positions := make(map[string]int)
+	for _, child := range parent.children {
+		name := child.name
+		if key := child.key(); key != "" {
+			name = fmt.Sprintf("%s[%s]", name, key)
+		} else if counts[child.name] > 1 {
+			name = fmt.Sprintf("%s[%d]", name, positions[child.name])
+		}
+		positions[child.name]++
+
+		path := name
+		if prefix != "" {
+			path = prefix + "/" + name
+		}
+
+		for _, attr := range child.attrs {
+			fields[path+"@"+attr.Name.Local] = attr.Value
+		}
+		if text := strings.TrimSpace(child.text); text != "" && len(child.children) == 0 {
+			fields[path] = text
+		}
+		flattenChildren(child, path, fields)
+	}
+}
+
+func (n *node) key() string {
+	if n.name != "Item" {
+		return ""
+	}
+	for _, key := range itemKeys {
+		for _, child := range n.children {
+			if child.name == key {
+				if text := strings.TrimSpace(child.text); text != "" {
+					return text
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,121 @@
+package xml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		name    string
+		xml     string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "text and attributes",
+			xml:  `<Root><name>adder</name><fMass value="1800.0" /></Root>`,
+			want: map[string]string{
+				"Root/name":        "adder",
+				"Root/fMass@value": "1800.0",
+			},
+		},
+		{
+			name: "items keyed by handlingName",
+			xml: `<CHandlingDataMgr><HandlingData>
+				<Item type="CHandlingData"><handlingName>adderH</handlingName><fMass value="1800.0" /></Item>
+				<Item type="CHandlingData"><handlingName>t20H</handlingName><fMass value="1400.0" /></Item>
+			</HandlingData></CHandlingDataMgr>`,
+			want: map[string]string{
+				"CHandlingDataMgr/HandlingData/Item[adderH]@type":         "CHandlingData",
+				"CHandlingDataMgr/HandlingData/Item[adderH]/handlingName": "adderH",
+				"CHandlingDataMgr/HandlingData/Item[adderH]/fMass@value":  "1800.0",
+				"CHandlingDataMgr/HandlingData/Item[t20H]@type":           "CHandlingData",
+				"CHandlingDataMgr/HandlingData/Item[t20H]/handlingName":   "t20H",
+				"CHandlingDataMgr/HandlingData/Item[t20H]/fMass@value":    "1400.0",
+			},
+		},
+		{
+			name: "items keyed by modelName first",
+			xml:  `<InitDatas><Item><handlingName>adderH</handlingName><modelName>adder</modelName></Item></InitDatas>`,
+			want: map[string]string{
+				"InitDatas/Item[adder]/handlingName": "adderH",
+				"InitDatas/Item[adder]/modelName":    "adder",
+			},
+		},
+		{
+			name: "items without a key by position",
+			xml:  `<colors><Item>1</Item><Item>2</Item><Item>3</Item></colors>`,
+			want: map[string]string{
+				"colors/Item[0]": "1",
+				"colors/Item[1]": "2",
+				"colors/Item[2]": "3",
+			},
+		},
+		{
+			name: "single element without position",
+			xml:  `<colors><Item>1</Item></colors>`,
+			want: map[string]string{"colors/Item": "1"},
+		},
+		{
+			name: "repeated elements other than Item",
+			xml:  `<flags><flag>A</flag><flag>B</flag></flags>`,
+			want: map[string]string{
+				"flags/flag[0]": "A",
+				"flags/flag[1]": "B",
+			},
+		},
+		{
+			name: "whitespace is trimmed and empty text skipped",
+			xml:  "<Root>\n  <name>  adder  </name>\n  <empty>   </empty>\n</Root>",
+			want: map[string]string{"Root/name": "adder"},
+		},
+		{
+			name: "text of elements with children is skipped",
+			xml:  `<Root>loose<name>adder</name></Root>`,
+			want: map[string]string{"Root/name": "adder"},
+		},
+		{
+			name: "declaration and comments",
+			xml:  `<?xml version="1.0" encoding="UTF-8"?><!-- generated --><Root><name>adder</name></Root>`,
+			want: map[string]string{"Root/name": "adder"},
+		},
+		{
+			name:    "broken document",
+			xml:     `<Root><name>adder</Root>`,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Flatten([]byte(test.xml))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Flatten() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Flatten() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGetStartTag(t *testing.T) {
+	tests := []struct {
+		xml  string
+		want string
+	}{
+		{xml: `<?xml version="1.0"?><CVehicleModelInfo__InitDataList></CVehicleModelInfo__InitDataList>`, want: "CVehicleModelInfo__InitDataList"},
+		{xml: `<!-- handling --><CHandlingDataMgr />`, want: "CHandlingDataMgr"},
+		{xml: ``, want: ""},
+	}
+	for _, test := range tests {
+		got, err := GetStartTag([]byte(test.xml))
+		if err != nil {
+			t.Errorf("GetStartTag(%q) = %v", test.xml, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("GetStartTag(%q) = %q, want %q", test.xml, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package watcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/carfinder"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/flags"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/inspector"
+	"github.com/ItzDabbzz/FiveMCarsMerger/pkg/merger"
+	"github.com/charmbracelet/log"
+)
+
+type Watcher interface {
+	Watch(ctx context.Context) error
+}
+
+type watcher struct {
+	Flags    flags.Flags
+	Interval time.Duration
+	Settle   time.Duration
+}
+
+type fileState struct {
+	Size    int64
+	ModTime time.Time
+}
+
+func New(_flags flags.Flags, interval time.Duration, settle time.Duration) Watcher {
+	return &watcher{
+		Flags:    _flags,
+		Interval: interval,
+		Settle:   settle,
+	}
+}
+
+// Watch merges once and then polls the input path, re-merging after changes have
+// settled for the configured duration. It returns when ctx is done.
+func (w *watcher) Watch(ctx context.Context) error {
+	snapshot, err := w.snapshot()
+	if err != nil {
+		return err
+	}
+	cars := w.build(ctx, nil)
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	var lastChange time.Time
+	pending := false
+
+	log.Info("Watching for changes", "paths", w.Flags.InputRoots(), "interval", w.Interval, "settle", w.Settle)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			current, err := w.snapshot()
+			if err != nil {
+				log.Error("Failed to read input path", "err", err)
+				continue
+			}
+			if !sameSnapshot(snapshot, current) {
+				log.Debug("Change detected, waiting for it to settle")
+				snapshot = current
+				lastChange = now
+				pending = true
+				continue
+			}
+			if pending && now.Sub(lastChange) >= w.Settle {
+				pending = false
+				cars = w.build(ctx, cars)
+			}
+		}
+	}
+}
+
+// build merges the input path and logs the cars that changed since previous.
+// It returns the car signatures of this build.
+func (w *watcher) build(ctx context.Context, previous map[string]string) map[string]string {
+	inventory, err := inspector.New(w.Flags).Inspect()
+	if err != nil {
+		log.Error("Failed to inspect input path", "err", err)
+		return previous
+	}
+	current := signatures(inventory.Cars)
+
+	if previous != nil {
+		added, changed, removed := compare(previous, current)
+		if len(added)+len(changed)+len(removed) == 0 {
+			log.Info("Files changed but no car did, merging anyway")
+		}
+		if len(added) > 0 {
+			log.Info("Cars added", "cars", added)
+		}
+		if len(changed) > 0 {
+			log.Info("Cars changed", "cars", changed)
+		}
+		if len(removed) > 0 {
+			log.Info("Cars removed", "cars", removed)
+		}
+	}
+
+	if err := merger.New(w.Flags).MergeContext(ctx); err != nil {
+		log.Error("Merge failed", "err", err)
+		return previous
+	}
+	return current
+}
+
+func (w *watcher) snapshot() (map[string]fileState, error) {
+	snapshot := make(map[string]fileState)
+	for _, root := range w.Flags.InputRoots() {
+		err := filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !f.IsDir() {
+				snapshot[path] = fileState{Size: f.Size(), ModTime: f.ModTime()}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+func sameSnapshot(a map[string]fileState, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, state := range a {
+		other, ok := b[path]
+		if !ok || other.Size != state.Size || !other.ModTime.Equal(state.ModTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// signatures hashes the path, size and modification time of every file of each car.
+func signatures(cars []*carfinder.Car) map[string]string {
+	result := make(map[string]string)
+	for _, car := range cars {
+		files := append(append(append([]string{}, car.StreamFiles...), car.DataFiles...), car.AudioFiles...)
+		sort.Strings(files)
+
+		hash := sha256.New()
+		for _, file := range files {
+			if info, err := os.Stat(file); err == nil {
+				fmt.Fprintf(hash, "%s\x00%d\x00%d\n", file, info.Size(), info.ModTime().UnixNano())
+			}
+		}
+		result[car.Model] = hex.EncodeToString(hash.Sum(nil))
+	}
+	return result
+}
+
+func compare(previous map[string]string, current map[string]string) (added []string, changed []string, removed []string) {
+	for model, signature := range current {
+		old, ok := previous[model]
+		switch {
+		case !ok:
+			added = append(added, model)
+		case old != signature:
+			changed = append(changed, model)
+		}
+	}
+	for model := range previous {
+		if _, ok := current[model]; !ok {
+			removed = append(removed, model)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return added, changed, removed
+}